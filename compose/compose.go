@@ -0,0 +1,165 @@
+// Package compose reads docker-compose files to build Avenues routes from the
+// services they define.
+package compose
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomicro/avenues/config"
+	"github.com/gomicro/avenues/labels"
+	"gopkg.in/yaml.v2"
+)
+
+// File represents the parts of a docker-compose file Avenues reads
+type File struct {
+	Services map[string]*Service `yaml:"services"`
+}
+
+// Service represents a single service of a docker-compose file
+type Service struct {
+	Ports  []interface{} `yaml:"ports"`
+	Expose []interface{} `yaml:"expose"`
+	Labels Labels        `yaml:"labels"`
+}
+
+// Labels represents service labels, which compose allows as either a map or a
+// list of key=value strings
+type Labels map[string]string
+
+// UnmarshalYAML reads labels from either of the forms compose allows
+func (l *Labels) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	m := map[string]string{}
+	if err := unmarshal(&m); err == nil {
+		*l = m
+		return nil
+	}
+
+	var list []string
+	err := unmarshal(&list)
+	if err != nil {
+		return fmt.Errorf("labels must be a map or a list: %v", err.Error())
+	}
+
+	m = map[string]string{}
+	for _, item := range list {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) == 2 {
+			m[kv[0]] = kv[1]
+		} else {
+			m[kv[0]] = ""
+		}
+	}
+
+	*l = m
+
+	return nil
+}
+
+// Load reads a docker-compose file from the given path
+func Load(path string) (*File, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %v", err.Error())
+	}
+
+	return Parse(b)
+}
+
+// Parse reads a docker-compose file from the provided bytes
+func Parse(b []byte) (*File, error) {
+	var f File
+	err := yaml.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal compose file: %v", err.Error())
+	}
+
+	return &f, nil
+}
+
+// Routes builds a route for every prefix listed in the avenues.route label of
+// each service. Backends address the service by name on its container port,
+// taken from the avenues.port label or the first port the service exposes.
+func (f *File) Routes() (map[string]*config.Route, error) {
+	names := make([]string, 0, len(f.Services))
+	for name := range f.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	routes := map[string]*config.Route{}
+	owners := map[string]string{}
+
+	for _, name := range names {
+		svc := f.Services[name]
+
+		if !labels.Routed(svc.Labels) {
+			continue
+		}
+
+		port, err := svc.port()
+		if err != nil {
+			return nil, fmt.Errorf("service %v: %v", name, err.Error())
+		}
+
+		for prefix, route := range labels.Routes(svc.Labels, name, port) {
+			if owner, ok := owners[prefix]; ok {
+				return nil, fmt.Errorf("route %v is claimed by both %v and %v", prefix, owner, name)
+			}
+
+			owners[prefix] = name
+			routes[prefix] = route
+		}
+	}
+
+	return routes, nil
+}
+
+func (s *Service) port() (int, error) {
+	port, ok, err := labels.PortOf(s.Labels)
+	if ok {
+		return port, err
+	}
+
+	for _, list := range [][]interface{}{s.Expose, s.Ports} {
+		for _, p := range list {
+			port, ok := containerPort(p)
+			if ok {
+				return port, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("no exposed ports and no %v label", labels.Port)
+}
+
+// containerPort reads the port inside the container from any of the port
+// syntaxes compose allows, such as 4567, "8080:4567", "127.0.0.1:8080:4567/tcp",
+// "4567-4570", or the long form with a target.
+func containerPort(p interface{}) (int, bool) {
+	switch t := p.(type) {
+	case int:
+		return t, true
+	case string:
+		parts := strings.Split(t, ":")
+		last := parts[len(parts)-1]
+		last = strings.SplitN(last, "/", 2)[0]
+		last = strings.SplitN(last, "-", 2)[0]
+
+		port, err := strconv.Atoi(last)
+		if err != nil {
+			return 0, false
+		}
+
+		return port, true
+	case map[interface{}]interface{}:
+		if target, ok := t["target"]; ok {
+			return containerPort(target)
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,110 @@
+package compose_test
+
+import (
+	"testing"
+
+	"github.com/gomicro/avenues/compose"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestCompose(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Compose", func() {
+		g.It("should build routes from labelled services", func() {
+			f, err := compose.Parse([]byte(`
+version: "3.8"
+services:
+  users:
+    image: users
+    expose:
+      - "4567"
+    labels:
+      avenues.route: /v1/users, /v1/teams
+  posts:
+    image: posts
+    ports:
+      - "127.0.0.1:8080:5000/tcp"
+    labels:
+      - avenues.route=/v1/posts
+      - avenues.scheme=https
+  billing:
+    image: billing
+    ports:
+      - target: 9000
+        published: 9000
+    labels:
+      avenues.route: /v1/billing
+      avenues.port: "9001"
+      avenues.type: static
+  database:
+    image: postgres
+    ports:
+      - 5432
+`))
+			Expect(err).To(BeNil())
+
+			routes, err := f.Routes()
+			Expect(err).To(BeNil())
+			Expect(len(routes)).To(Equal(4))
+
+			Expect(routes["/v1/users"].Backend).To(Equal("http://users:4567"))
+			Expect(routes["/v1/teams"].Backend).To(Equal("http://users:4567"))
+			Expect(routes["/v1/posts"].Backend).To(Equal("https://posts:5000"))
+			Expect(routes["/v1/billing"].Backend).To(Equal("http://billing:9001"))
+			Expect(routes["/v1/billing"].Type).To(Equal("static"))
+		})
+
+		g.It("should read the long port syntax", func() {
+			f, err := compose.Parse([]byte(`
+services:
+  users:
+    ports:
+      - target: 4567
+        published: 8080
+    labels:
+      avenues.route: /v1/users
+`))
+			Expect(err).To(BeNil())
+
+			routes, err := f.Routes()
+			Expect(err).To(BeNil())
+			Expect(routes["/v1/users"].Backend).To(Equal("http://users:4567"))
+		})
+
+		g.It("should require a port", func() {
+			f, err := compose.Parse([]byte(`
+services:
+  users:
+    labels:
+      avenues.route: /v1/users
+`))
+			Expect(err).To(BeNil())
+
+			_, err = f.Routes()
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("users"))
+		})
+
+		g.It("should reject routes claimed by multiple services", func() {
+			f, err := compose.Parse([]byte(`
+services:
+  users:
+    expose: [4567]
+    labels:
+      avenues.route: /v1/users
+  accounts:
+    expose: [4567]
+    labels:
+      avenues.route: /v1/users
+`))
+			Expect(err).To(BeNil())
+
+			_, err = f.Routes()
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gomicro/avenues/compose"
+	"github.com/gomicro/avenues/config"
+	log "github.com/gomicro/ledger"
+)
+
+// composeRoutes builds routes from the avenues labels of the services in a
+// docker-compose file, and either writes them as a routes file or serves them
+// directly.
+func composeRoutes(args []string) {
+	fs := flag.NewFlagSet("compose", flag.ExitOnError)
+	file := fs.String("f", "docker-compose.yml", "docker-compose file to read")
+	out := fs.String("o", "", "file to write the routes to, defaults to stdout")
+	serveRoutes := fs.Bool("serve", false, "serve the routes instead of writing them")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: avenues compose [-f docker-compose.yml] [-o routes.yaml | -serve]")
+		fs.PrintDefaults()
+	}
+
+	_ = fs.Parse(args)
+
+	cf, err := compose.Load(*file)
+	if err != nil {
+		fatalf("Failed to load compose file: %v", err.Error())
+	}
+
+	routes, err := cf.Routes()
+	if err != nil {
+		fatalf("Failed to build routes: %v", err.Error())
+	}
+
+	if !*serveRoutes {
+		writeRoutes(routes, *out)
+		return
+	}
+
+	logVersion()
+
+	c, err := config.New(routes)
+	if err != nil {
+		log.Fatalf("Failed to configure routes: %v", err.Error())
+		os.Exit(1)
+	}
+
+	conf = c
+	log.Debugf("%v routes read from compose file", len(routes))
+
+	serve()
+}
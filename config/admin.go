@@ -0,0 +1,375 @@
+package config
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/gomicro/ledger"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	configSource = "config"
+	adminSource  = "admin"
+
+	defaultAdminAddress = "0.0.0.0:4568"
+)
+
+// Admin represents where the admin endpoints are served. They are served on
+// their own address unless InBand also serves them alongside proxied traffic,
+// and require the Token as a bearer token when one is set.
+type Admin struct {
+	Address   string `yaml:"address,omitempty"`
+	Token     string `yaml:"token,omitempty"`
+	TokenPath string `yaml:"token_path,omitempty"`
+	InBand    bool   `yaml:"in_band,omitempty"`
+}
+
+func loadAdmin(a *Admin) error {
+	if a.Address == "" {
+		a.Address = defaultAdminAddress
+	}
+
+	if a.TokenPath != "" {
+		b, err := ioutil.ReadFile(a.TokenPath)
+		if err != nil {
+			return fmt.Errorf("Failed to read admin token from file: %v", err.Error())
+		}
+		a.Token = strings.TrimSpace(string(b))
+	}
+
+	return nil
+}
+
+// AdminHandler returns the handler for the admin address, which answers only
+// the admin endpoints
+func (f *File) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodOptions {
+			setCORSHeaders(w.Header())
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if !f.serveAdmin(w, req) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+// serveAdmin answers requests for the admin endpoints, reporting whether the
+// request was for one of them. The status endpoint and the dashboard's page
+// are left open so health checks and browsers can reach them without the
+// token.
+func (f *File) serveAdmin(w http.ResponseWriter, req *http.Request) bool {
+	handler, open := f.adminEndpoint(req.URL.Path)
+	if handler == nil {
+		return false
+	}
+
+	if !open && !f.Admin.authorized(req) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="avenues"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return true
+	}
+
+	handler(w, req)
+
+	return true
+}
+
+// adminEndpoint returns the handler for the admin endpoint at the path, if
+// any, and whether it is open without the token
+func (f *File) adminEndpoint(path string) (http.HandlerFunc, bool) {
+	switch path {
+	case f.Status:
+		return handleStatus, true
+	case f.Reset:
+		return f.handleReset, false
+	case f.Faults:
+		return f.handleFaults, false
+	case f.HAR:
+		return f.handleHAR, false
+	case f.RoutesAPI:
+		return f.handleRoutes, false
+	case f.Requests:
+		return f.handleRequests, false
+	case f.Verify:
+		return f.handleVerify, false
+	case f.Stream:
+		return f.handleStream, false
+	case f.SnapshotAPI:
+		return f.handleSnapshot, false
+	case f.Breakpoints:
+		return f.handleBreakpoints, false
+	}
+
+	if strings.HasPrefix(path, f.Breakpoints+"/") {
+		return f.handleBreakpoint, false
+	}
+
+	if strings.HasPrefix(path, f.RoutesAPI+"/") {
+		return f.handleRoute, false
+	}
+
+	if path == f.Dashboard || strings.HasPrefix(path, f.Dashboard+"/") {
+		return f.handleDashboard, true
+	}
+
+	return nil, false
+}
+
+// authorized reports whether the request carries the admin token, either as
+// a bearer token or, for clients such as EventSource that cannot set
+// headers, as the token query parameter
+func (a *Admin) authorized(req *http.Request) bool {
+	if a.Token == "" {
+		return true
+	}
+
+	token := req.URL.Query().Get("token")
+	if h := req.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) == 1
+}
+
+// routeError represents the most recent failure serving a route
+type routeError struct {
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+}
+
+// routeState represents a route and what it has been doing, as reported by
+// the routes endpoint
+type routeState struct {
+	Prefix    string      `json:"prefix"`
+	Type      string      `json:"type"`
+	Source    string      `json:"source"`
+	Backends  []string    `json:"backends,omitempty"`
+	Index     *int        `json:"index,omitempty"`
+	Hits      int64       `json:"hits"`
+	LastError *routeError `json:"last_error,omitempty"`
+}
+
+func (f *File) handleRoutes(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"routes": f.routeStates()})
+}
+
+// handleRoute reads, creates, replaces, or removes the single route whose
+// prefix follows the routes endpoint in the request path.
+func (f *File) handleRoute(w http.ResponseWriter, req *http.Request) {
+	prefix := strings.TrimPrefix(req.URL.Path, f.RoutesAPI)
+
+	switch req.Method {
+	case http.MethodGet:
+		state, ok := f.routeState(prefix)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, state)
+	case http.MethodPut:
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read route: %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		var route Route
+		err = yaml.Unmarshal(b, &route)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to unmarshal route: %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		created, err := f.PutRoute(prefix, &route)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+
+		state, _ := f.routeState(prefix)
+		writeJSON(w, status, state)
+	case http.MethodDelete:
+		if !f.DeleteRoute(prefix) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// PutRoute loads the route and, only if it is valid, adds it under the
+// prefix, replacing any route already there. It reports whether the route
+// was newly created.
+func (f *File) PutRoute(prefix string, route *Route) (bool, error) {
+	if !strings.HasPrefix(prefix, "/") {
+		return false, fmt.Errorf("route prefix must begin with '/'")
+	}
+
+	err := f.loadRoute(route)
+	if err != nil {
+		return false, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, exists := f.Routes[prefix]
+	f.Routes[prefix] = route
+	f.sources[prefix] = adminSource
+
+	if exists {
+		log.Infof("replaced route '%v' from %v", prefix, adminSource)
+	} else {
+		log.Infof("added route '%v' from %v", prefix, adminSource)
+	}
+
+	return !exists, nil
+}
+
+// DeleteRoute removes the route under the prefix, reporting whether there was
+// one to remove.
+func (f *File) DeleteRoute(prefix string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.Routes[prefix]; !ok {
+		return false
+	}
+
+	delete(f.Routes, prefix)
+	delete(f.sources, prefix)
+	log.Infof("removed route '%v' from %v", prefix, adminSource)
+
+	return true
+}
+
+func (f *File) routeState(prefix string) (*routeState, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	route, ok := f.Routes[prefix]
+	if !ok {
+		return nil, false
+	}
+
+	return route.state(prefix, f.source(prefix)), true
+}
+
+func (f *File) routeStates() []*routeState {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	states := make([]*routeState, 0, len(f.Routes))
+	for prefix, route := range f.Routes {
+		states = append(states, route.state(prefix, f.source(prefix)))
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Prefix < states[j].Prefix
+	})
+
+	return states
+}
+
+// source names where the route under the prefix came from. The caller must
+// hold the lock.
+func (f *File) source(prefix string) string {
+	if s, ok := f.sources[prefix]; ok {
+		return s
+	}
+
+	return configSource
+}
+
+func (r *Route) state(prefix, source string) *routeState {
+	typ := strings.ToLower(r.Type)
+	if typ == "" {
+		typ = staticRouteType
+	}
+
+	s := &routeState{
+		Prefix:   prefix,
+		Type:     typ,
+		Source:   source,
+		Backends: r.backends(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if typ == ordinalRouteType {
+		i := r.index
+		s.Index = &i
+	}
+
+	s.Hits = r.hits
+	if r.lastError != nil {
+		e := *r.lastError
+		s.LastError = &e
+	}
+
+	return s
+}
+
+// backends lists every address the route may send requests to
+func (r *Route) backends() []string {
+	var backends []string
+	seen := map[string]bool{}
+
+	add := func(b string) {
+		if b != "" && !seen[b] {
+			seen[b] = true
+			backends = append(backends, b)
+		}
+	}
+
+	add(r.Backend)
+	for _, b := range r.Backends {
+		add(b)
+	}
+
+	for _, rule := range r.Operations {
+		add(rule.Backend)
+	}
+
+	for _, rule := range r.Methods {
+		add(rule.Backend)
+	}
+
+	return backends
+}
+
+func (r *Route) hit() {
+	r.mu.Lock()
+	r.hits++
+	r.mu.Unlock()
+}
+
+func (r *Route) fail(err error) {
+	r.mu.Lock()
+	r.lastError = &routeError{Message: err.Error(), At: time.Now().UTC()}
+	r.mu.Unlock()
+}
@@ -0,0 +1,371 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+type routeState struct {
+	Prefix    string   `json:"prefix"`
+	Type      string   `json:"type"`
+	Source    string   `json:"source"`
+	Backends  []string `json:"backends"`
+	Index     *int     `json:"index"`
+	Hits      int64    `json:"hits"`
+	LastError *struct {
+		Message string `json:"message"`
+		At      string `json:"at"`
+	} `json:"last_error"`
+}
+
+func TestRoutesAPI(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	g.Describe("Routes endpoint", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+  /v1/posts:
+    type: ordinal
+    backends:
+      - %v
+      - %v
+  /v1/broken:
+    backend: %v
+`, backend.URL, backend.URL, closed.URL, closed.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		states := func() map[string]*routeState {
+			resp, err := http.Get(admin.URL + "/avenues/routes")
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			var body struct {
+				Routes []*routeState `json:"routes"`
+			}
+			Expect(json.NewDecoder(resp.Body).Decode(&body)).To(BeNil())
+
+			Expect(body.Routes).To(HaveLen(3))
+			Expect(body.Routes[0].Prefix).To(Equal("/v1/broken"))
+
+			m := map[string]*routeState{}
+			for _, s := range body.Routes {
+				m[s.Prefix] = s
+			}
+
+			return m
+		}
+
+		g.It("should describe every route", func() {
+			s := states()
+
+			Expect(s["/v1/users"].Type).To(Equal("static"))
+			Expect(s["/v1/users"].Source).To(Equal("config"))
+			Expect(s["/v1/users"].Backends).To(Equal([]string{backend.URL}))
+			Expect(s["/v1/users"].Index).To(BeNil())
+			Expect(s["/v1/users"].Hits).To(Equal(int64(0)))
+			Expect(s["/v1/users"].LastError).To(BeNil())
+
+			Expect(s["/v1/posts"].Type).To(Equal("ordinal"))
+			Expect(s["/v1/posts"].Backends).To(Equal([]string{backend.URL, closed.URL}))
+			Expect(*s["/v1/posts"].Index).To(Equal(0))
+		})
+
+		g.It("should report hits, ordinal indices, and errors", func() {
+			for i := 0; i < 3; i++ {
+				resp, err := http.Get(server.URL + "/v1/users/1")
+				Expect(err).To(BeNil())
+				resp.Body.Close()
+			}
+
+			resp, err := http.Get(server.URL + "/v1/posts")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			resp, err = http.Get(server.URL + "/v1/broken")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+
+			s := states()
+			Expect(s["/v1/users"].Hits).To(Equal(int64(3)))
+			Expect(*s["/v1/posts"].Index).To(Equal(1))
+			Expect(s["/v1/broken"].Hits).To(Equal(int64(1)))
+			Expect(s["/v1/broken"].LastError).NotTo(BeNil())
+			Expect(s["/v1/broken"].LastError.Message).To(ContainSubstring("connection refused"))
+			Expect(s["/v1/broken"].LastError.At).NotTo(BeEmpty())
+		})
+
+		g.It("should only allow reads", func() {
+			resp, err := http.Post(admin.URL+"/avenues/routes", "application/json", nil)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+}
+
+func TestRouteManagement(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	g.Describe("Managing routes at runtime", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+`, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		do := func(s *httptest.Server, method, path, body string) *http.Response {
+			req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+			Expect(err).To(BeNil())
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			return resp
+		}
+
+		g.It("should create and remove routes", func() {
+			resp := do(server, http.MethodGet, "/v1/teams", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+			resp = do(admin, http.MethodPut, "/avenues/routes/v1/teams", fmt.Sprintf(`{"backend": %q}`, backend.URL))
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+			resp = do(server, http.MethodGet, "/v1/teams/1", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+
+			r, err := http.Get(admin.URL + "/avenues/routes/v1/teams")
+			Expect(err).To(BeNil())
+			var state routeState
+			Expect(json.NewDecoder(r.Body).Decode(&state)).To(BeNil())
+			r.Body.Close()
+			Expect(state.Source).To(Equal("admin"))
+			Expect(state.Hits).To(Equal(int64(1)))
+
+			resp = do(admin, http.MethodDelete, "/avenues/routes/v1/teams", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			resp = do(server, http.MethodGet, "/v1/teams", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+			resp = do(admin, http.MethodDelete, "/avenues/routes/v1/teams", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should replace routes from the config file", func() {
+			resp := do(admin, http.MethodPut, "/avenues/routes/v1/users", `
+type: redirect
+target: /v2/users
+`)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			client := &http.Client{
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			r, err := client.Get(server.URL + "/v1/users")
+			Expect(err).To(BeNil())
+			r.Body.Close()
+			Expect(r.StatusCode).To(Equal(http.StatusFound))
+			Expect(r.Header.Get("Location")).To(Equal("/v2/users"))
+		})
+
+		g.It("should leave routes untouched when the new route is invalid", func() {
+			resp := do(admin, http.MethodPut, "/avenues/routes/v1/users", `{"type": "redirect"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+			resp = do(admin, http.MethodPut, "/avenues/routes/v1/users", `{"type": `)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+			resp = do(server, http.MethodGet, "/v1/users", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		})
+
+		g.It("should reject routes without usable backends", func() {
+			for _, body := range []string{
+				`{"type": "statik", "backend": "http://service:4567"}`,
+				`{}`,
+				`{"backend": "::nope"}`,
+				`{"backend": "service:4567/path"}`,
+				`{"backend": "/relative"}`,
+				`{"type": "ordinal", "backends": []}`,
+				`{"type": "ordinal"}`,
+				`{"type": "ordinal", "backends": ["http://service:4567", "::nope"]}`,
+				`{"type": "jsonrpc", "methods": [{"prefix": "eth_", "backend": "nope"}]}`,
+			} {
+				resp := do(admin, http.MethodPut, "/avenues/routes/v1/x", body)
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), body)
+			}
+
+			resp := do(server, http.MethodGet, "/v1/x", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+}
+
+func TestAdminListener(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	status := func(req *http.Request) int {
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).To(BeNil())
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	get := func(u string) int {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		Expect(err).To(BeNil())
+
+		return status(req)
+	}
+
+	g.Describe("Admin listener", func() {
+		g.It("should keep admin endpoints off the proxy by default", func() {
+			c, err := config.Parse([]byte(`
+routes: {}
+`))
+			Expect(err).To(BeNil())
+			Expect(c.Admin.Address).To(Equal("0.0.0.0:4568"))
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			admin := httptest.NewServer(c.AdminHandler())
+			defer admin.Close()
+
+			Expect(get(server.URL + "/avenues/routes")).To(Equal(http.StatusNotFound))
+			Expect(get(server.URL + "/avenues/status")).To(Equal(http.StatusNotFound))
+			Expect(get(admin.URL + "/avenues/routes")).To(Equal(http.StatusOK))
+			Expect(get(admin.URL + "/avenues/status")).To(Equal(http.StatusOK))
+			Expect(get(admin.URL + "/v1/users")).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should serve admin endpoints alongside proxied traffic in band", func() {
+			c, err := config.Parse([]byte(`
+routes: {}
+admin:
+  in_band: true
+`))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			Expect(get(server.URL + "/avenues/routes")).To(Equal(http.StatusOK))
+			Expect(get(server.URL + "/avenues/status")).To(Equal(http.StatusOK))
+		})
+
+		g.It("should require the token", func() {
+			f, err := ioutil.TempFile("", "token")
+			Expect(err).To(BeNil())
+			defer os.Remove(f.Name())
+
+			_, err = f.WriteString("s3cret\n")
+			Expect(err).To(BeNil())
+			f.Close()
+
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes: {}
+admin:
+  token_path: %v
+  in_band: true
+`, f.Name())))
+			Expect(err).To(BeNil())
+
+			for _, h := range []http.Handler{c.AdminHandler(), c} {
+				server := httptest.NewServer(h)
+
+				resp, err := http.Get(server.URL + "/avenues/routes")
+				Expect(err).To(BeNil())
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(resp.Header.Get("WWW-Authenticate")).To(ContainSubstring("Bearer"))
+
+				req, _ := http.NewRequest(http.MethodGet, server.URL+"/avenues/routes", nil)
+				req.Header.Set("Authorization", "Bearer wrong")
+				Expect(status(req)).To(Equal(http.StatusUnauthorized))
+
+				req, _ = http.NewRequest(http.MethodGet, server.URL+"/avenues/routes", nil)
+				req.Header.Set("Authorization", "Bearer s3cret")
+				Expect(status(req)).To(Equal(http.StatusOK))
+
+				Expect(get(server.URL + "/avenues/requests?token=s3cret")).To(Equal(http.StatusOK))
+				Expect(get(server.URL + "/avenues/status")).To(Equal(http.StatusOK))
+				Expect(get(server.URL + "/avenues/ui/")).To(Equal(http.StatusOK))
+
+				server.Close()
+			}
+		})
+
+		g.It("should fail to load a missing token file", func() {
+			_, err := config.Parse([]byte(`
+routes: {}
+admin:
+  token_path: /does/not/exist
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
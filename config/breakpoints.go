@@ -0,0 +1,471 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/gomicro/ledger"
+)
+
+const (
+	defaultBreakpointTimeout = 5 * time.Minute
+
+	pausedPath = "paused"
+)
+
+// Breakpoint represents a rule pausing the requests its matcher selects until
+// they are released through the breakpoints endpoint. A paused request that
+// is not released within the Timeout, five minutes by default, continues
+// unchanged.
+type Breakpoint struct {
+	ID      int64          `json:"id"`
+	Request RequestMatcher `json:"request"`
+	Timeout string         `json:"timeout,omitempty"`
+	timeout time.Duration
+}
+
+// PausedRequest represents a request held by a breakpoint before its route
+// serves it. Backend is the backend the route would send it to, when it has
+// one, which the type of the route may still change.
+type PausedRequest struct {
+	ID         int64       `json:"id"`
+	Breakpoint int64       `json:"breakpoint"`
+	Time       time.Time   `json:"time"`
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Query      string      `json:"query,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Route      string      `json:"route"`
+	Backend    string      `json:"backend,omitempty"`
+	release    chan *Release
+}
+
+// Release represents how a paused request continues. Headers and Body, when
+// given, replace those of the request before its route serves it, or before
+// it is proxied to Backend in place of the route. A Response answers the
+// request in place of the route.
+type Release struct {
+	Headers  http.Header `json:"headers,omitempty"`
+	Body     *string     `json:"body,omitempty"`
+	Backend  string      `json:"backend,omitempty"`
+	Response *Response   `json:"response,omitempty"`
+}
+
+func (b *Breakpoint) load() error {
+	err := b.Request.validate()
+	if err != nil {
+		return err
+	}
+
+	if b.Request.Status != "" || !b.Request.Since.IsZero() || !b.Request.Until.IsZero() {
+		return fmt.Errorf("breakpoints pause requests before they are answered and cannot match status or time")
+	}
+
+	b.timeout = defaultBreakpointTimeout
+	if b.Timeout != "" {
+		b.timeout, err = time.ParseDuration(b.Timeout)
+		if err != nil {
+			return fmt.Errorf("failed to parse timeout: %v", err.Error())
+		}
+
+		if b.timeout <= 0 {
+			return fmt.Errorf("timeout must be positive")
+		}
+	}
+	b.Timeout = b.timeout.String()
+
+	return nil
+}
+
+func (r *Release) load() error {
+	if r.Response != nil && r.Backend != "" {
+		return fmt.Errorf("a response cannot be combined with a backend")
+	}
+
+	if r.Backend != "" {
+		u, err := url.Parse(r.Backend)
+		if err != nil {
+			return fmt.Errorf("failed to parse backend: %v", err.Error())
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("backend must be an absolute url")
+		}
+	}
+
+	return nil
+}
+
+// apply edits the request with the headers and body of the release
+func (r *Release) apply(req *http.Request) {
+	if r.Headers != nil {
+		h := make(http.Header, len(r.Headers))
+		for k, vs := range r.Headers {
+			for _, v := range vs {
+				h.Add(k, v)
+			}
+		}
+
+		req.Header = h
+	}
+
+	if r.Body != nil {
+		setBody(req, []byte(*r.Body))
+	}
+}
+
+func setBody(req *http.Request, b []byte) {
+	req.ContentLength = int64(len(b))
+	req.Header.Del("Content-Length")
+
+	if len(b) == 0 {
+		req.Body = http.NoBody
+		return
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+}
+
+// breakpointSet keeps the breakpoints and the requests they have paused
+type breakpointSet struct {
+	sync.RWMutex
+	rules      map[int64]*Breakpoint
+	paused     map[int64]*PausedRequest
+	nextRule   int64
+	nextPaused int64
+}
+
+func newBreakpointSet() *breakpointSet {
+	return &breakpointSet{
+		rules:  make(map[int64]*Breakpoint),
+		paused: make(map[int64]*PausedRequest),
+	}
+}
+
+// find returns the first breakpoint selecting the journal entry of a request
+func (s *breakpointSet) find(e *JournalEntry) (*Breakpoint, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	var found *Breakpoint
+	for _, b := range s.rules {
+		if b.Request.matches(e) && (found == nil || b.ID < found.ID) {
+			found = b
+		}
+	}
+
+	return found, found != nil
+}
+
+// pause holds the request for the route until it is released, its breakpoint
+// times out, or its client goes away, in which case no release is returned.
+// The release is applied to the request before it is returned.
+func (s *breakpointSet) pause(b *Breakpoint, req *http.Request, prefix, backend string) (*Release, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body: %v", err.Error())
+		}
+
+		setBody(req, body)
+	}
+
+	p := &PausedRequest{
+		Breakpoint: b.ID,
+		Time:       time.Now().UTC(),
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Query:      req.URL.RawQuery,
+		Headers:    req.Header.Clone(),
+		Body:       string(body),
+		Route:      prefix,
+		Backend:    backend,
+		release:    make(chan *Release, 1),
+	}
+
+	s.Lock()
+	s.nextPaused++
+	p.ID = s.nextPaused
+	s.paused[p.ID] = p
+	s.Unlock()
+
+	log.Infof("paused request %v for '%v' at breakpoint %v", p.ID, req.URL, b.ID)
+
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+
+	var r *Release
+	select {
+	case r = <-p.release:
+	case <-timer.C:
+		r = s.expire(p, &Release{})
+		log.Infof("paused request %v timed out", p.ID)
+	case <-req.Context().Done():
+		r = s.expire(p, nil)
+	}
+
+	if r != nil {
+		r.apply(req)
+	}
+
+	return r, nil
+}
+
+// expire removes the paused request, returning the given fallback unless it
+// was released in the meantime
+func (s *breakpointSet) expire(p *PausedRequest, fallback *Release) *Release {
+	_, ok := s.take(p.ID)
+	if !ok {
+		return <-p.release
+	}
+
+	return fallback
+}
+
+func (s *breakpointSet) take(id int64) (*PausedRequest, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	p, ok := s.paused[id]
+	if ok {
+		delete(s.paused, id)
+	}
+
+	return p, ok
+}
+
+// release continues the paused request, reporting whether it was still paused
+func (s *breakpointSet) release(id int64, r *Release) bool {
+	p, ok := s.take(id)
+	if !ok {
+		return false
+	}
+
+	p.release <- r
+	log.Infof("released paused request %v", id)
+
+	return true
+}
+
+func (s *breakpointSet) add(b *Breakpoint) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.nextRule++
+	b.ID = s.nextRule
+	s.rules[b.ID] = b
+}
+
+func (s *breakpointSet) get(id int64) (*Breakpoint, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	b, ok := s.rules[id]
+	return b, ok
+}
+
+func (s *breakpointSet) list() []*Breakpoint {
+	s.RLock()
+	defer s.RUnlock()
+
+	rules := make([]*Breakpoint, 0, len(s.rules))
+	for _, b := range s.rules {
+		rules = append(rules, b)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	return rules
+}
+
+// remove deletes the breakpoint, releasing the requests it paused unchanged
+func (s *breakpointSet) remove(id int64) bool {
+	s.Lock()
+	_, ok := s.rules[id]
+	delete(s.rules, id)
+	s.Unlock()
+
+	if ok {
+		s.releaseAll(func(p *PausedRequest) bool { return p.Breakpoint == id })
+	}
+
+	return ok
+}
+
+// clear deletes every breakpoint, releasing every paused request unchanged
+func (s *breakpointSet) clear() {
+	s.Lock()
+	s.rules = make(map[int64]*Breakpoint)
+	s.Unlock()
+
+	s.releaseAll(func(*PausedRequest) bool { return true })
+}
+
+func (s *breakpointSet) releaseAll(selected func(*PausedRequest) bool) {
+	for _, p := range s.pausedRequests() {
+		if selected(p) {
+			s.release(p.ID, &Release{})
+		}
+	}
+}
+
+func (s *breakpointSet) pausedRequest(id int64) (*PausedRequest, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	p, ok := s.paused[id]
+	return p, ok
+}
+
+// pausedRequests returns the paused requests, oldest first
+func (s *breakpointSet) pausedRequests() []*PausedRequest {
+	s.RLock()
+	defer s.RUnlock()
+
+	paused := make([]*PausedRequest, 0, len(s.paused))
+	for _, p := range s.paused {
+		paused = append(paused, p)
+	}
+
+	sort.Slice(paused, func(i, j int) bool {
+		return paused[i].ID < paused[j].ID
+	})
+
+	return paused
+}
+
+func (f *File) handleBreakpoints(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, f.breakpoints.list())
+	case http.MethodPost:
+		var b Breakpoint
+		err := json.NewDecoder(req.Body).Decode(&b)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode breakpoint: %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err = b.load()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.breakpoints.add(&b)
+		log.Infof("breakpoint %v set", b.ID)
+
+		writeJSON(w, http.StatusCreated, &b)
+	case http.MethodDelete:
+		f.breakpoints.clear()
+		log.Info("all breakpoints cleared")
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBreakpoint serves a single breakpoint at its id, and the requests
+// paused by breakpoints beneath the paused path
+func (f *File) handleBreakpoint(w http.ResponseWriter, req *http.Request) {
+	rest := strings.TrimPrefix(req.URL.Path, f.Breakpoints+"/")
+
+	if rest == pausedPath {
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, f.breakpoints.pausedRequests())
+		return
+	}
+
+	if strings.HasPrefix(rest, pausedPath+"/") {
+		f.handlePaused(w, req, strings.TrimPrefix(rest, pausedPath+"/"))
+		return
+	}
+
+	id, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		b, ok := f.breakpoints.get(id)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, b)
+	case http.MethodDelete:
+		if !f.breakpoints.remove(id) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		log.Infof("breakpoint %v cleared", id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *File) handlePaused(w http.ResponseWriter, req *http.Request, rest string) {
+	id, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		p, ok := f.breakpoints.pausedRequest(id)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, p)
+	case http.MethodPost:
+		var r Release
+		err := json.NewDecoder(req.Body).Decode(&r)
+		if err != nil && err != io.EOF {
+			http.Error(w, fmt.Sprintf("failed to decode release: %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err = r.load()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !f.breakpoints.release(id, &r) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
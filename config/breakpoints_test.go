@@ -0,0 +1,351 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestBreakpoints(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	echo := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			fmt.Fprintf(w, "%v|%v|%v", name, r.Header.Get("X-Debug"), string(b))
+		}))
+	}
+
+	backend := echo("backend")
+	defer backend.Close()
+
+	other := echo("other")
+	defer other.Close()
+
+	g.Describe("Breakpoints", func() {
+		var server, admin *httptest.Server
+		var dir string
+
+		g.BeforeEach(func() {
+			d, err := ioutil.TempDir("", "breakpoints")
+			Expect(err).To(BeNil())
+			dir = d
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0644)).To(BeNil())
+
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+  /v1/posts:
+    backend: %v
+  /v1/teams:
+    type: ordinal
+    backends:
+      - %v
+      - %v
+  /app:
+    type: static_dir
+    dir: %v
+`, backend.URL, backend.URL, other.URL, backend.URL, dir)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+			os.RemoveAll(dir)
+		})
+
+		do := func(s *httptest.Server, method, path, body string) (int, string) {
+			req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+			Expect(err).To(BeNil())
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).To(BeNil())
+
+			return resp.StatusCode, string(b)
+		}
+
+		type result struct {
+			status int
+			body   string
+		}
+
+		// send makes the request in the background, as it will be paused
+		send := func(method, path, body string) <-chan result {
+			done := make(chan result, 1)
+			go func() {
+				req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+				req.Header.Set("X-Debug", "original")
+
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					done <- result{}
+					return
+				}
+				defer resp.Body.Close()
+
+				b, _ := ioutil.ReadAll(resp.Body)
+				done <- result{resp.StatusCode, string(b)}
+			}()
+
+			return done
+		}
+
+		paused := func() []*config.PausedRequest {
+			var ps []*config.PausedRequest
+			Eventually(func() int {
+				_, body := do(admin, http.MethodGet, "/avenues/breakpoints/paused", "")
+				Expect(json.Unmarshal([]byte(body), &ps)).To(BeNil())
+				return len(ps)
+			}, "2s", "10ms").ShouldNot(BeZero())
+
+			return ps
+		}
+
+		g.It("should pause matching requests until they are released", func() {
+			status, body := do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users", "method": "POST"}}`)
+			Expect(status).To(Equal(http.StatusCreated))
+			Expect(body).To(ContainSubstring(`"id":1`))
+			Expect(body).To(ContainSubstring(`"timeout":"5m0s"`))
+
+			status, body = do(server, http.MethodGet, "/v1/users", "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("backend||"))
+
+			status, body = do(server, http.MethodPost, "/v1/posts", "hello")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("backend||hello"))
+
+			done := send(http.MethodPost, "/v1/users", "hello")
+
+			ps := paused()
+			Expect(ps).To(HaveLen(1))
+			Expect(ps[0].Breakpoint).To(Equal(int64(1)))
+			Expect(ps[0].Route).To(Equal("/v1/users"))
+			Expect(ps[0].Backend).To(Equal(backend.URL))
+			Expect(ps[0].Headers.Get("X-Debug")).To(Equal("original"))
+			Expect(ps[0].Body).To(Equal("hello"))
+
+			status, body = do(admin, http.MethodGet, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), "")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"body":"hello"`))
+
+			Consistently(done, "100ms").ShouldNot(Receive())
+
+			status, _ = do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), "")
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			var r result
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.status).To(Equal(http.StatusOK))
+			Expect(r.body).To(Equal("backend|original|hello"))
+
+			status, _ = do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), "")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should send edited requests to a chosen backend", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"path": "/v1/users/{id}"}}`)
+
+			done := send(http.MethodPut, "/v1/users/1", "hello")
+			ps := paused()
+
+			release := fmt.Sprintf(`{"headers": {"x-debug": ["edited"]}, "body": "goodbye", "backend": %q}`, other.URL)
+			status, _ := do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), release)
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			var r result
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.body).To(Equal("other|edited|goodbye"))
+
+			_, body := do(admin, http.MethodGet, "/avenues/requests?path=/v1/users/{id}", "")
+			Expect(body).To(ContainSubstring(fmt.Sprintf(`"backend":"%v/v1/users/1"`, other.URL)))
+		})
+
+		g.It("should answer with a canned response", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users"}}`)
+
+			done := send(http.MethodGet, "/v1/users", "")
+			ps := paused()
+
+			release := `{"response": {"status": 418, "body": "paused and answered"}}`
+			status, _ := do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), release)
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			var r result
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.status).To(Equal(http.StatusTeapot))
+			Expect(r.body).To(Equal("paused and answered"))
+		})
+
+		g.It("should pause requests for routes served without a backend", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/app"}}`)
+
+			done := send(http.MethodGet, "/app/", "")
+			ps := paused()
+			Expect(ps[0].Backend).To(BeEmpty())
+
+			do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), "")
+
+			var r result
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.status).To(Equal(http.StatusOK))
+			Expect(r.body).To(Equal("<html>app</html>"))
+
+			done = send(http.MethodGet, "/app/", "")
+			ps = paused()
+
+			status, _ := do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), `{"response": {"status": 503}}`)
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.status).To(Equal(http.StatusServiceUnavailable))
+
+			done = send(http.MethodGet, "/app/", "")
+			ps = paused()
+
+			do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), fmt.Sprintf(`{"backend": %q}`, other.URL))
+
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.body).To(Equal("other|original|"))
+		})
+
+		g.It("should show the backend an ordinal route would pick", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/teams"}}`)
+
+			for _, want := range []struct {
+				url, name string
+			}{{other.URL, "other"}, {backend.URL, "backend"}, {backend.URL, "backend"}} {
+				done := send(http.MethodGet, "/v1/teams", "")
+				ps := paused()
+				Expect(ps[0].Backend).To(Equal(want.url))
+
+				do(admin, http.MethodPost, fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID), "")
+
+				var r result
+				Eventually(done, "2s").Should(Receive(&r))
+				Expect(r.body).To(Equal(want.name + "|original|"))
+			}
+		})
+
+		g.It("should journal requests abandoned while paused as unanswered", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users"}}`)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/users", nil)
+			go func() {
+				resp, err := http.DefaultClient.Do(req)
+				if err == nil {
+					resp.Body.Close()
+				}
+			}()
+
+			paused()
+			cancel()
+
+			Eventually(func() string {
+				_, body := do(admin, http.MethodGet, "/avenues/requests?route=/v1/users", "")
+				return body
+			}, "2s", "10ms").Should(ContainSubstring(`"abandoned":true`))
+
+			_, body := do(admin, http.MethodGet, "/avenues/requests?route=/v1/users", "")
+			Expect(body).To(ContainSubstring(`"status":0`))
+		})
+
+		g.It("should not pause requests for routes that are down", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users"}}`)
+
+			status, _ := do(admin, http.MethodPost, "/avenues/faults", `{"route": "/v1/users", "down": true}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _ = do(server, http.MethodGet, "/v1/users", "")
+			Expect(status).To(Equal(http.StatusServiceUnavailable))
+
+			_, body := do(admin, http.MethodGet, "/avenues/breakpoints/paused", "")
+			Expect(strings.TrimSpace(body)).To(Equal("[]"))
+		})
+
+		g.It("should continue requests unchanged after the timeout", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users"}, "timeout": "200ms"}`)
+
+			start := time.Now()
+			status, body := do(server, http.MethodPost, "/v1/users", "hello")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("backend||hello"))
+			Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+
+			_, body = do(admin, http.MethodGet, "/avenues/breakpoints/paused", "")
+			Expect(strings.TrimSpace(body)).To(Equal("[]"))
+		})
+
+		g.It("should release paused requests when their breakpoint is removed", func() {
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users"}}`)
+
+			done := send(http.MethodPost, "/v1/users", "hello")
+			paused()
+
+			status, _ := do(admin, http.MethodDelete, "/avenues/breakpoints/1", "")
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			var r result
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.body).To(Equal("backend|original|hello"))
+
+			status, _ = do(admin, http.MethodGet, "/avenues/breakpoints/1", "")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			_, body := do(admin, http.MethodGet, "/avenues/breakpoints", "")
+			Expect(strings.TrimSpace(body)).To(Equal("[]"))
+		})
+
+		g.It("should reject invalid breakpoints and releases", func() {
+			status, _ := do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"status": "5xx"}}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users"}, "timeout": "soon"}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			do(admin, http.MethodPost, "/avenues/breakpoints", `{"request": {"route": "/v1/users"}}`)
+
+			done := send(http.MethodGet, "/v1/users", "")
+			ps := paused()
+			path := fmt.Sprintf("/avenues/breakpoints/paused/%v", ps[0].ID)
+
+			status, _ = do(admin, http.MethodPost, path, `{"backend": "not a url"}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = do(admin, http.MethodPost, path, fmt.Sprintf(`{"backend": %q, "response": {"status": 200}}`, other.URL))
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = do(admin, http.MethodDelete, "/avenues/breakpoints", "")
+			Expect(status).To(Equal(http.StatusNoContent))
+
+			var r result
+			Eventually(done, "2s").Should(Receive(&r))
+			Expect(r.body).To(Equal("backend|original|"))
+		})
+	})
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/gomicro/ledger"
+)
+
+const (
+	defaultCallbackMethod     = http.MethodPost
+	defaultCallbackRetryDelay = time.Second
+	callbackTimeout           = 30 * time.Second
+)
+
+// Callback represents an outbound request fired after a route responds. The
+// url, headers, and body are templates given the original request.
+type Callback struct {
+	URL        string            `yaml:"url"`
+	Method     string            `yaml:"method,omitempty"`
+	Headers    map[string]string `yaml:"headers,omitempty"`
+	Body       string            `yaml:"body,omitempty"`
+	Delay      time.Duration     `yaml:"delay,omitempty"`
+	Retries    int               `yaml:"retries,omitempty"`
+	RetryDelay time.Duration     `yaml:"retry_delay,omitempty"`
+	url        *template.Template
+	headers    map[string]*template.Template
+	body       *template.Template
+}
+
+// callbackData is what callback templates are executed with
+type callbackData struct {
+	Method  string
+	Path    string
+	Query   url.Values
+	Headers http.Header
+	Body    string
+	JSON    interface{}
+	Status  int
+}
+
+func loadCallbacks(callbacks []*Callback) error {
+	for i, c := range callbacks {
+		if c.URL == "" {
+			return fmt.Errorf("callback %v requires url directive", i)
+		}
+
+		if c.Method == "" {
+			c.Method = defaultCallbackMethod
+		}
+
+		if c.RetryDelay == 0 {
+			c.RetryDelay = defaultCallbackRetryDelay
+		}
+
+		var err error
+		c.url, err = template.New("url").Parse(c.URL)
+		if err != nil {
+			return fmt.Errorf("failed to parse callback %v url: %v", i, err.Error())
+		}
+
+		c.body, err = template.New("body").Parse(c.Body)
+		if err != nil {
+			return fmt.Errorf("failed to parse callback %v body: %v", i, err.Error())
+		}
+
+		c.headers = map[string]*template.Template{}
+		for k, v := range c.Headers {
+			c.headers[k], err = template.New(k).Parse(v)
+			if err != nil {
+				return fmt.Errorf("failed to parse callback %v header %v: %v", i, k, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// newCallbackData captures the request for the callback templates, restoring
+// the request body so it can still be served.
+func newCallbackData(req *http.Request) (*callbackData, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &callbackData{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Query:   req.URL.Query(),
+		Headers: req.Header.Clone(),
+		Body:    string(body),
+	}
+
+	var v interface{}
+	if json.Unmarshal(body, &v) == nil {
+		data.JSON = v
+	}
+
+	return data, nil
+}
+
+// fireCallbacks sends each of the route's callbacks in the background
+func (f *File) fireCallbacks(route *Route, data *callbackData) {
+	client := &http.Client{
+		Transport: f.transport,
+		Timeout:   callbackTimeout,
+	}
+
+	for _, c := range route.Callbacks {
+		go c.fire(client, data)
+	}
+}
+
+func (c *Callback) fire(client *http.Client, data *callbackData) {
+	time.Sleep(c.Delay)
+
+	u, err := execute(c.url, data)
+	if err != nil {
+		log.Errorf("failed to build callback url: %v", err.Error())
+		return
+	}
+
+	body, err := execute(c.body, data)
+	if err != nil {
+		log.Errorf("failed to build callback body: %v", err.Error())
+		return
+	}
+
+	headers := http.Header{}
+	for k, t := range c.headers {
+		v, err := execute(t, data)
+		if err != nil {
+			log.Errorf("failed to build callback header %v: %v", k, err.Error())
+			return
+		}
+
+		headers.Set(k, v)
+	}
+
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.RetryDelay)
+		}
+
+		err = send(client, c.Method, u, headers, body)
+		if err == nil {
+			log.Infof("fired callback '%v %v'", c.Method, u)
+			return
+		}
+
+		log.Warnf("callback '%v %v' attempt %v failed: %v", c.Method, u, attempt+1, err.Error())
+	}
+
+	log.Errorf("callback '%v %v' failed after %v attempts", c.Method, u, c.Retries+1)
+}
+
+func send(client *http.Client, method, u string, headers http.Header, body string) error {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err.Error())
+	}
+	req.Header = headers
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+
+	return nil
+}
+
+func execute(t *template.Template, data *callbackData) (string, error) {
+	var b bytes.Buffer
+	err := t.Execute(&b, data)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,145 @@
+package config_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+type received struct {
+	method string
+	path   string
+	header string
+	body   string
+}
+
+func TestCallbacks(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	var mu sync.Mutex
+	var calls []*received
+	failures := 0
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+		calls = append(calls, &received{
+			method: r.Method,
+			path:   r.URL.Path,
+			header: r.Header.Get("X-Signature"),
+			body:   string(b),
+		})
+	}))
+	defer target.Close()
+
+	callCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(calls)
+	}
+
+	g.Describe("Callbacks", func() {
+		var server *httptest.Server
+
+		g.BeforeEach(func() {
+			mu.Lock()
+			calls = nil
+			failures = 0
+			mu.Unlock()
+
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/payments:
+    backend: %v
+    callbacks:
+      - url: "%v/hooks/{{.JSON.order}}"
+        headers:
+          X-Signature: "sig-{{.Headers.Get \"X-Request-Id\"}}"
+        body: '{"order": "{{.JSON.order}}", "status": "paid", "upstream": {{.Status}}}'
+        delay: 100ms
+        retries: 2
+        retry_delay: 10ms
+`, backend.URL, target.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+		})
+
+		pay := func() {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/payments", bytes.NewBufferString(`{"order": "ord-1"}`))
+			Expect(err).To(BeNil())
+			req.Header.Set("X-Request-Id", "abc")
+
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+		}
+
+		g.It("should fire a templated callback after a delay", func() {
+			pay()
+
+			Consistently(callCount, 50*time.Millisecond).Should(Equal(0))
+			Eventually(callCount, time.Second).Should(Equal(1))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			Expect(calls[0].method).To(Equal(http.MethodPost))
+			Expect(calls[0].path).To(Equal("/hooks/ord-1"))
+			Expect(calls[0].header).To(Equal("sig-abc"))
+			Expect(calls[0].body).To(MatchJSON(`{"order": "ord-1", "status": "paid", "upstream": 202}`))
+		})
+
+		g.It("should retry failed callbacks", func() {
+			mu.Lock()
+			failures = 2
+			mu.Unlock()
+
+			pay()
+
+			Eventually(callCount, time.Second).Should(Equal(1))
+		})
+
+		g.It("should reject callbacks without a url", func() {
+			_, err := config.Parse([]byte(`
+routes:
+  /v1/payments:
+    backend: http://payments:4567
+    callbacks:
+      - body: "{}"
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Cassette represents a set of recorded interactions with backends
+type Cassette struct {
+	Interactions []*Interaction `yaml:"interactions"`
+}
+
+// Interaction represents a single recorded request and response pair
+type Interaction struct {
+	Route      string            `yaml:"route"`
+	RecordedAt time.Time         `yaml:"recorded_at"`
+	Request    *RecordedRequest  `yaml:"request"`
+	Response   *RecordedResponse `yaml:"response"`
+}
+
+// RecordedRequest represents the request half of an interaction
+type RecordedRequest struct {
+	Method  string      `yaml:"method"`
+	Host    string      `yaml:"host,omitempty"`
+	Path    string      `yaml:"path"`
+	Query   string      `yaml:"query,omitempty"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+// RecordedResponse represents the response half of an interaction
+type RecordedResponse struct {
+	Status  int         `yaml:"status"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    string      `yaml:"body,omitempty"`
+}
+
+// LoadCassette reads a cassette from the given file
+func LoadCassette(path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %v", err.Error())
+	}
+
+	var c Cassette
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cassette: %v", err.Error())
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to the given file, creating any missing
+// directories along the way.
+func (c *Cassette) Save(path string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %v", err.Error())
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create cassette directory: %v", err.Error())
+	}
+
+	err = ioutil.WriteFile(path, b, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %v", err.Error())
+	}
+
+	return nil
+}
+
+// marshalInteractions writes the interactions as the items of a cassette's
+// interactions, ready to be appended to a saved cassette
+func marshalInteractions(in ...*Interaction) ([]byte, error) {
+	b, err := yaml.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal interactions: %v", err.Error())
+	}
+
+	return b, nil
+}
+
+// appendInteractions adds marshalled interactions to the end of a cassette
+// file written by Save, which always ends with its interactions
+func appendInteractions(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cassette: %v", err.Error())
+	}
+
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cassette: %v", err.Error())
+	}
+
+	return f.Close()
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,33 +10,63 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/gomicro/avenues/openapi"
 	log "github.com/gomicro/ledger"
 	"github.com/gomicro/trust"
 	"gopkg.in/yaml.v2"
 )
 
 const (
-	defaultStatusEndpoint = "/avenues/status"
-	defaultResetEndpoint  = "/avenues/reset"
-	defaultConfigFile     = "./routes.yaml"
+	defaultStatusEndpoint   = "/avenues/status"
+	defaultResetEndpoint    = "/avenues/reset"
+	defaultFaultsEndpoint   = "/avenues/faults"
+	defaultHAREndpoint      = "/avenues/har"
+	defaultRoutesEndpoint   = "/avenues/routes"
+	defaultRequestsEndpoint = "/avenues/requests"
+	defaultVerifyEndpoint   = "/avenues/verify"
+	defaultStreamEndpoint   = "/avenues/stream"
+	defaultDashboard        = "/avenues/ui"
+	defaultSnapshotEndpoint = "/avenues/snapshot"
+	defaultBreakpoints      = "/avenues/breakpoints"
+	defaultConfigFile       = "./routes.yaml"
 
 	configFileEnv = "AVENUES_CONFIG_FILE"
 )
 
 // File represents all the configurable options of Avenues
 type File struct {
-	Routes    map[string]*Route                 `yaml:"routes"`
-	Reset     string                            `yaml:"reset"`
-	Status    string                            `yaml:"status"`
-	Cert      string                            `yaml:"cert"`
-	CertPath  string                            `yaml:"cert_path"`
-	Key       string                            `yaml:"key"`
-	KeyPath   string                            `yaml:"key_path"`
-	CA        string                            `yaml:"ca"`
-	CAPath    string                            `yaml:"ca_path"`
-	proxies   map[string]*httputil.ReverseProxy `yaml:"-"`
-	transport *http.Transport                   `yaml:"-"`
+	Routes      map[string]*Route                 `yaml:"routes"`
+	Reset       string                            `yaml:"reset"`
+	Status      string                            `yaml:"status"`
+	Faults      string                            `yaml:"faults"`
+	HAR         string                            `yaml:"har"`
+	RoutesAPI   string                            `yaml:"routes_api"`
+	Requests    string                            `yaml:"requests"`
+	Verify      string                            `yaml:"verify"`
+	Stream      string                            `yaml:"stream"`
+	Dashboard   string                            `yaml:"dashboard"`
+	SnapshotAPI string                            `yaml:"snapshot"`
+	Breakpoints string                            `yaml:"breakpoints"`
+	Admin       *Admin                            `yaml:"admin,omitempty"`
+	Cert        string                            `yaml:"cert"`
+	CertPath    string                            `yaml:"cert_path"`
+	Key         string                            `yaml:"key"`
+	KeyPath     string                            `yaml:"key_path"`
+	CA          string                            `yaml:"ca"`
+	CAPath      string                            `yaml:"ca_path"`
+	Record      *Record                           `yaml:"record,omitempty"`
+	Discovery   *Discovery                        `yaml:"discovery,omitempty"`
+	Journal     *Journal                          `yaml:"journal,omitempty"`
+	proxies     map[string]*httputil.ReverseProxy `yaml:"-"`
+	transport   *http.Transport                   `yaml:"-"`
+	faults      *faultSet                         `yaml:"-"`
+	breakpoints *breakpointSet                    `yaml:"-"`
+	recorder    *recorder                         `yaml:"-"`
+	journal     *requestJournal                   `yaml:"-"`
+	mu          sync.RWMutex                      `yaml:"-"`
+	sources     map[string]string                 `yaml:"-"`
 }
 
 // ParseFromFile reads an Avenues config file from the file specified in the
@@ -54,100 +85,327 @@ func ParseFromFile() (*File, error) {
 		return nil, fmt.Errorf("Failed to read config file: %v", err.Error())
 	}
 
+	return Parse(b)
+}
+
+// Parse reads an Avenues config from the provided bytes. A File with the
+// populated values is returned and any errors encountered while parsing.
+func Parse(b []byte) (*File, error) {
 	var conf File
-	err = yaml.Unmarshal(b, &conf)
+	err := yaml.Unmarshal(b, &conf)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to unmarshal config file: %v", err.Error())
 	}
 
-	if conf.Status == "" {
-		conf.Status = defaultStatusEndpoint
+	err = conf.init()
+	if err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// New creates a File for the given routes with every other option left at
+// its default.
+func New(routes map[string]*Route) (*File, error) {
+	conf := &File{Routes: routes}
+
+	err := conf.init()
+	if err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// init applies defaults, loads any referenced files, and prepares the File
+// for serving.
+func (f *File) init() error {
+	if f.Status == "" {
+		f.Status = defaultStatusEndpoint
+	}
+
+	if f.Reset == "" {
+		f.Reset = defaultResetEndpoint
+	}
+
+	if f.Faults == "" {
+		f.Faults = defaultFaultsEndpoint
 	}
 
-	if conf.Reset == "" {
-		conf.Reset = defaultResetEndpoint
+	if f.HAR == "" {
+		f.HAR = defaultHAREndpoint
 	}
 
-	conf.proxies = make(map[string]*httputil.ReverseProxy)
+	if f.RoutesAPI == "" {
+		f.RoutesAPI = defaultRoutesEndpoint
+	}
+
+	if f.Requests == "" {
+		f.Requests = defaultRequestsEndpoint
+	}
 
-	if conf.KeyPath != "" {
-		key, err := ioutil.ReadFile(conf.KeyPath)
+	if f.Verify == "" {
+		f.Verify = defaultVerifyEndpoint
+	}
+
+	if f.Stream == "" {
+		f.Stream = defaultStreamEndpoint
+	}
+
+	if f.Dashboard == "" {
+		f.Dashboard = defaultDashboard
+	}
+
+	if f.SnapshotAPI == "" {
+		f.SnapshotAPI = defaultSnapshotEndpoint
+	}
+
+	if f.Breakpoints == "" {
+		f.Breakpoints = defaultBreakpoints
+	}
+
+	if f.Admin == nil {
+		f.Admin = &Admin{}
+	}
+
+	err := loadAdmin(f.Admin)
+	if err != nil {
+		return err
+	}
+
+	if f.Routes == nil {
+		f.Routes = make(map[string]*Route)
+	}
+
+	f.proxies = make(map[string]*httputil.ReverseProxy)
+	f.faults = newFaultSet()
+	f.breakpoints = newBreakpointSet()
+	f.sources = make(map[string]string)
+
+	if f.Record == nil {
+		f.Record = &Record{}
+	}
+
+	if f.Journal == nil {
+		f.Journal = &Journal{}
+	}
+
+	f.journal, err = newRequestJournal(f.Journal)
+	if err != nil {
+		return fmt.Errorf("Failed to configure journal: %v", err.Error())
+	}
+
+	rec, err := newRecorder(f.Record, f.Journal.Size)
+	if err != nil {
+		return fmt.Errorf("Failed to configure recording: %v", err.Error())
+	}
+	f.recorder = rec
+
+	for prefix, route := range f.Routes {
+		err = f.loadRoute(route)
+		if err != nil {
+			return fmt.Errorf("Failed to load route '%v': %v", prefix, err.Error())
+		}
+	}
+
+	if f.KeyPath != "" {
+		key, err := ioutil.ReadFile(f.KeyPath)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to read Key from file: %v", err.Error())
+			return fmt.Errorf("Failed to read Key from file: %v", err.Error())
 		}
-		conf.Key = string(key)
+		f.Key = string(key)
 	}
 
-	if conf.CertPath != "" {
-		cert, err := ioutil.ReadFile(conf.CertPath)
+	if f.CertPath != "" {
+		cert, err := ioutil.ReadFile(f.CertPath)
 		if err != nil {
-			return nil, fmt.Errorf("Ffailed to read Cert from file: %v", err.Error())
+			return fmt.Errorf("Ffailed to read Cert from file: %v", err.Error())
 		}
-		conf.Cert = string(cert)
+		f.Cert = string(cert)
 	}
 
-	if conf.CAPath != "" {
-		ca, err := ioutil.ReadFile(conf.CAPath)
+	if f.CAPath != "" {
+		ca, err := ioutil.ReadFile(f.CAPath)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to read CA(s) from file: %v", err.Error())
+			return fmt.Errorf("Failed to read CA(s) from file: %v", err.Error())
 		}
-		conf.CA = string(ca)
+		f.CA = string(ca)
 	}
 
 	pool := trust.New()
 
 	certs, err := pool.CACerts()
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get default CA cert pool")
+		return fmt.Errorf("Failed to get default CA cert pool")
 	}
 
-	if conf.CA != "" {
-		ok := certs.AppendCertsFromPEM([]byte(conf.CA))
+	if f.CA != "" {
+		ok := certs.AppendCertsFromPEM([]byte(f.CA))
 		if !ok {
-			return nil, fmt.Errorf("Failed to append CA(s) to cert pool")
+			return fmt.Errorf("Failed to append CA(s) to cert pool")
 		}
 	}
 
-	conf.transport = &http.Transport{
+	f.transport = &http.Transport{
 		MaxIdleConnsPerHost: 50,
 		MaxIdleConns:        50,
 		TLSClientConfig:     &tls.Config{RootCAs: certs},
 	}
 
-	return &conf, nil
+	return nil
 }
 
 func (f *File) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if req.Method == "OPTIONS" {
 		log.Info("responding with cors headers for options request")
 
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "*, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "60")
-		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, post-check=0, pre-check=0")
-		w.Header().Set("Vary", "Accept-Encoding")
+		setCORSHeaders(w.Header())
 		w.WriteHeader(http.StatusNoContent)
 
 		return
 	}
 
-	switch req.URL.Path {
-	case f.Status:
-		handleStatus(w, req)
+	if f.Admin.InBand && f.serveAdmin(w, req) {
 		return
-	case f.Reset:
-		f.handleReset(w, req)
+	}
+
+	entry := f.journal.begin(req)
+	jw := &statusWriter{ResponseWriter: w}
+	w = jw
+
+	defer func() {
+		f.journal.finish(entry, jw.status)
+	}()
+
+	prefix, route, ok := f.pathToRoute(req.URL.Path)
+	if !ok {
+		log.Warnf("failed to proxy url: route not found for url: %v", req.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	route.hit()
+	entry.Route = prefix
+
+	if len(route.Callbacks) > 0 {
+		data, err := newCallbackData(req)
+		if err != nil {
+			log.Warnf("failed to read request for callbacks: %v", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+		w = sw
+
+		defer func() {
+			data.Status = sw.status
+			f.fireCallbacks(route, data)
+		}()
+	}
+
+	if f.injectFault(w, req, prefix, nil, entry) {
 		return
 	}
 
-	u, err := f.backingURL(req.URL)
+	if route.Throttle != nil {
+		req.Body = route.Throttle.reader(req.Body)
+		w = route.Throttle.writer(w)
+	}
+
+	var backend string
+
+	if bp, ok := f.breakpoints.find(entry); ok {
+		release, err := f.breakpoints.pause(bp, req, prefix, route.nextBackend())
+		if err != nil {
+			log.Warnf("failed to pause request: %v", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if release == nil {
+			log.Infof("paused request for '%v' went away", req.URL)
+			entry.Abandoned = true
+			return
+		}
+
+		if release.Response != nil {
+			release.Response.write(w)
+			return
+		}
+
+		backend = release.Backend
+	}
+
+	// a backend chosen for a released request is proxied to in place of
+	// serving the request by the route's type
+	if backend == "" {
+		switch strings.ToLower(route.Type) {
+		case staticDirRouteType:
+			serveDir(w, req, prefix, route)
+			return
+		case replayRouteType:
+			if f.replay(w, req, route) {
+				return
+			}
+		case redirectRouteType:
+			if redirect(w, req, prefix, route) {
+				return
+			}
+		case openAPIRouteType:
+			if mockOpenAPI(w, req, route) {
+				return
+			}
+		case graphQLRouteType:
+			var handled bool
+			backend, handled = routeGraphQL(w, req, route)
+			if handled {
+				return
+			}
+		case jsonRPCRouteType:
+			var handled bool
+			backend, handled = f.routeJSONRPC(w, req, prefix, route, entry)
+			if handled {
+				return
+			}
+		}
+	}
+
+	var u *url.URL
+	var err error
+	if backend != "" {
+		u, err = targetURL(backend, req.URL)
+	} else {
+		u, err = route.backingURL(req.URL)
+	}
 	if err != nil {
 		log.Warnf("failed to proxy url: %v", err.Error())
+		route.fail(err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
+	entry.Backend = u.String()
+
+	if f.injectFault(w, req, prefix, u, entry) {
+		return
+	}
+
+	if route.OpenAPI != nil && route.OpenAPI.request(w, req) {
+		return
+	}
+
+	var in *Interaction
+	if route.captures() {
+		in, err = newInteraction(prefix, req)
+		if err != nil {
+			log.Warnf("failed to record request: %v", err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
 	rp := httputil.ReverseProxy{
 		Director: func(req *http.Request) {
 			req.Header.Add("X-Forwarded-Host", req.Host)
@@ -159,25 +417,63 @@ func (f *File) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		},
 		Transport: f.transport,
 		ModifyResponse: func(resp *http.Response) error {
-			resp.Header.Set("Access-Control-Allow-Origin", "*")
-			resp.Header.Set("Access-Control-Allow-Methods", "*")
-			resp.Header.Set("Access-Control-Allow-Headers", "*, Authorization")
-			resp.Header.Set("Access-Control-Max-Age", "60")
-			resp.Header.Set("Cache-Control", "no-store, no-cache, must-revalidate, post-check=0, pre-check=0")
-			resp.Header.Set("Vary", "Accept-Encoding")
+			if in != nil {
+				err := in.captureResponse(resp)
+				if err != nil {
+					return err
+				}
+
+				err = f.recordInteraction(route, in)
+				if err != nil {
+					log.Errorf("failed to record interaction: %v", err.Error())
+				}
+			}
+
+			if route.OpenAPI != nil {
+				err := route.OpenAPI.response(req, resp)
+				if err != nil {
+					return err
+				}
+			}
+
+			setCORSHeaders(resp.Header)
+
+			// upgrades take over the connection rather than writing a header
+			if resp.StatusCode == http.StatusSwitchingProtocols && jw.status == 0 {
+				jw.status = resp.StatusCode
+			}
 
 			return nil
 		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			log.Errorf("failed to proxy '%v' to '%v': %v", req.URL, u.String(), err.Error())
+			route.fail(err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
 	}
 
 	rp.ServeHTTP(w, req)
 	log.Infof("proxyed '%v' to '%v'", req.URL, u.String())
 }
 
+func setCORSHeaders(h http.Header) {
+	h.Set("Access-Control-Allow-Origin", "*")
+	h.Set("Access-Control-Allow-Methods", "*")
+	h.Set("Access-Control-Allow-Headers", "*, Authorization")
+	h.Set("Access-Control-Max-Age", "60")
+	h.Set("Cache-Control", "no-store, no-cache, must-revalidate, post-check=0, pre-check=0")
+	h.Set("Vary", "Accept-Encoding")
+}
+
 func (f *File) handleReset(w http.ResponseWriter, req *http.Request) {
+	f.mu.RLock()
 	for _, r := range f.Routes {
 		r.reset()
 	}
+	f.mu.RUnlock()
+
+	f.recorder.clearCaptured()
+
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte("routes have been reset"))
 	if err != nil {
@@ -193,36 +489,41 @@ func handleStatus(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func (f *File) backingURL(reqURL *url.URL) (*url.URL, error) {
-	route, ok := f.pathToRoute(reqURL.Path)
-	if !ok {
-		return nil, fmt.Errorf("route not found for url: %v", reqURL.Path)
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Errorf("internal error writing json: %v", err.Error())
 	}
+}
 
+func (route *Route) backingURL(reqURL *url.URL) (*url.URL, error) {
 	var u *url.URL
 	var err error
 
 	switch strings.ToLower(route.Type) {
 	case ordinalRouteType:
-		i := route.index
-
 		if route.Backends == nil {
 			return nil, fmt.Errorf("ordinal route requires backends directive")
 		}
 
-		u, err = url.Parse(route.Backends[i])
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse service address: %v", err.Error())
-		}
-
+		route.mu.Lock()
+		i := route.index
 		if i < len(route.Backends)-1 {
 			route.index++
 		}
-	case staticRouteType, "":
-		u, err = url.Parse(route.Backend)
+		route.mu.Unlock()
+
+		u, err = url.Parse(route.Backends[i])
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse service address: %v", err.Error())
 		}
+	case staticRouteType, replayRouteType, redirectRouteType, openAPIRouteType, graphQLRouteType, jsonRPCRouteType, "":
+		return targetURL(route.Backend, reqURL)
+	default:
+		return nil, fmt.Errorf("unknown route type: %v", route.Type)
 	}
 
 	u.Path = reqURL.Path
@@ -231,14 +532,46 @@ func (f *File) backingURL(reqURL *url.URL) (*url.URL, error) {
 	return u, nil
 }
 
-func (f *File) pathToRoute(path string) (*Route, bool) {
+// nextBackend returns the backend the route would send its next request to,
+// without moving an ordinal route on to its following backend
+func (route *Route) nextBackend() string {
+	if strings.ToLower(route.Type) != ordinalRouteType {
+		return route.Backend
+	}
+
+	route.mu.Lock()
+	defer route.mu.Unlock()
+
+	if route.index < len(route.Backends) {
+		return route.Backends[route.index]
+	}
+
+	return ""
+}
+
+func targetURL(backend string, reqURL *url.URL) (*url.URL, error) {
+	u, err := url.Parse(backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service address: %v", err.Error())
+	}
+
+	u.Path = reqURL.Path
+	u.RawQuery = reqURL.Query().Encode()
+
+	return u, nil
+}
+
+func (f *File) pathToRoute(path string) (string, *Route, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	for prefix, route := range f.Routes {
 		if strings.HasPrefix(trailingSlash(path), prefix) {
-			return route, true
+			return prefix, route, true
 		}
 	}
 
-	return nil, false
+	return "", nil, false
 }
 
 func trailingSlash(path string) string {
@@ -250,18 +583,161 @@ func trailingSlash(path string) string {
 }
 
 const (
-	ordinalRouteType = "ordinal"
-	staticRouteType  = "static"
+	ordinalRouteType   = "ordinal"
+	staticRouteType    = "static"
+	replayRouteType    = "replay"
+	staticDirRouteType = "static_dir"
+	redirectRouteType  = "redirect"
+	openAPIRouteType   = "openapi"
+	graphQLRouteType   = "graphql"
+	jsonRPCRouteType   = "jsonrpc"
 )
 
 // Route represents a backing route to direct a request to
 type Route struct {
-	Type     string   `yaml:"type"`
-	Backend  string   `yaml:"backend,omitempty"`
-	index    int      `yaml:"-"`
-	Backends []string `yaml:"backends,omitempty"`
+	Type          string            `yaml:"type"`
+	Backend       string            `yaml:"backend,omitempty"`
+	index         int               `yaml:"-"`
+	Backends      []string          `yaml:"backends,omitempty"`
+	Throttle      *Throttle         `yaml:"throttle,omitempty"`
+	Record        bool              `yaml:"record,omitempty"`
+	Capture       bool              `yaml:"capture,omitempty"`
+	Cassette      string            `yaml:"cassette,omitempty"`
+	HAR           string            `yaml:"har,omitempty"`
+	MatchBody     bool              `yaml:"match_body,omitempty"`
+	Unmatched     string            `yaml:"unmatched,omitempty"`
+	Dir           string            `yaml:"dir,omitempty"`
+	SPA           bool              `yaml:"spa,omitempty"`
+	Target        string            `yaml:"target,omitempty"`
+	Status        int               `yaml:"status,omitempty"`
+	From          string            `yaml:"from,omitempty"`
+	PreserveQuery bool              `yaml:"preserve_query,omitempty"`
+	Spec          string            `yaml:"spec,omitempty"`
+	spec          *openapi.Document `yaml:"-"`
+	OpenAPI       *Validation       `yaml:"openapi,omitempty"`
+	Callbacks     []*Callback       `yaml:"callbacks,omitempty"`
+	Operations    []*GraphQLRule    `yaml:"operations,omitempty"`
+	Methods       []*JSONRPCRule    `yaml:"methods,omitempty"`
+	mu            sync.Mutex        `yaml:"-"`
+	hits          int64             `yaml:"-"`
+	lastError     *routeError       `yaml:"-"`
+}
+
+func (f *File) loadRoute(route *Route) error {
+	err := loadBackends(route)
+	if err != nil {
+		return err
+	}
+
+	err = loadCallbacks(route.Callbacks)
+	if err != nil {
+		return err
+	}
+
+	if route.OpenAPI != nil {
+		err := loadValidation(route.OpenAPI)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch strings.ToLower(route.Type) {
+	case replayRouteType:
+		return f.loadReplay(route)
+	case staticDirRouteType:
+		return loadStaticDir(route)
+	case redirectRouteType:
+		return loadRedirect(route)
+	case openAPIRouteType:
+		return loadOpenAPI(route)
+	case graphQLRouteType:
+		return loadGraphQL(route)
+	case jsonRPCRouteType:
+		return loadJSONRPC(route)
+	}
+
+	return nil
+}
+
+// loadBackends checks the route is of a known type and has the backends it
+// needs, each an absolute url
+func loadBackends(route *Route) error {
+	switch strings.ToLower(route.Type) {
+	case staticRouteType, "":
+		if route.Backend == "" {
+			return fmt.Errorf("static route requires backend directive")
+		}
+	case ordinalRouteType:
+		if len(route.Backends) == 0 {
+			return fmt.Errorf("ordinal route requires backends directive")
+		}
+
+		for _, backend := range route.Backends {
+			err := checkBackend(backend)
+			if err != nil {
+				return fmt.Errorf("invalid ordinal backend: %v", err.Error())
+			}
+		}
+	case replayRouteType, staticDirRouteType, redirectRouteType, openAPIRouteType, graphQLRouteType, jsonRPCRouteType:
+	default:
+		return fmt.Errorf("unknown route type: %v", route.Type)
+	}
+
+	if route.Backend != "" {
+		err := checkBackend(route.Backend)
+		if err != nil {
+			return fmt.Errorf("invalid backend: %v", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// checkBackend reports an error unless the backend is an absolute url
+func checkBackend(backend string) error {
+	u, err := url.Parse(backend)
+	if err != nil {
+		return fmt.Errorf("failed to parse service address: %v", err.Error())
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("service address must be an absolute url: %v", backend)
+	}
+
+	return nil
+}
+
+func (f *File) recordInteraction(route *Route, in *Interaction) error {
+	if !route.records() {
+		f.recorder.export(in)
+		return nil
+	}
+
+	if strings.ToLower(route.Type) == replayRouteType && route.Unmatched == recordUnmatched {
+		return f.recorder.save(route.Cassette, in)
+	}
+
+	return f.recorder.record(in)
+}
+
+// records reports whether requests proxied through the route are written to
+// cassettes
+func (r *Route) records() bool {
+	if strings.ToLower(r.Type) == replayRouteType && r.Unmatched == recordUnmatched {
+		return true
+	}
+
+	return r.Record
+}
+
+// captures reports whether requests proxied through the route are kept for
+// exporting, which every route that records does
+func (r *Route) captures() bool {
+	return r.Capture || r.records()
 }
 
 func (r *Route) reset() {
+	r.mu.Lock()
 	r.index = 0
+	r.mu.Unlock()
 }
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	log "github.com/gomicro/ledger"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+var dashboardIndex = template.Must(template.ParseFS(dashboardFiles, "dashboard/index.html"))
+
+// dashboardEndpoints are the admin endpoints the dashboard calls, which it is
+// given since each may be configured
+type dashboardEndpoints struct {
+	Routes   string `json:"routes"`
+	Requests string `json:"requests"`
+	Stream   string `json:"stream"`
+	Faults   string `json:"faults"`
+	Reset    string `json:"reset"`
+}
+
+// handleDashboard serves the web dashboard's page and the assets it loads
+func (f *File) handleDashboard(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// assets are referenced relative to the page, so it is served as a directory
+	if req.URL.Path == f.Dashboard {
+		http.Redirect(w, req, f.Dashboard+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, f.Dashboard+"/")
+	if name == "" || name == "index.html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		err := dashboardIndex.Execute(w, &dashboardEndpoints{
+			Routes:   f.RoutesAPI,
+			Requests: f.Requests,
+			Stream:   f.Stream,
+			Faults:   f.Faults,
+			Reset:    f.Reset,
+		})
+		if err != nil {
+			log.Errorf("internal error rendering dashboard: %v", err.Error())
+		}
+
+		return
+	}
+
+	assets, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		log.Errorf("internal error reading dashboard: %v", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	http.StripPrefix(f.Dashboard+"/", http.FileServer(http.FS(assets))).ServeHTTP(w, req)
+}
@@ -0,0 +1,192 @@
+(function () {
+  'use strict';
+
+  const maxRequests = 200;
+  const requests = [];
+  const expanded = new Set();
+
+  // the admin token, when one is required, is taken from the page's query or
+  // asked for once and kept for the session
+  const token = new URLSearchParams(window.location.search).get('token') || sessionStorage.getItem('avenues-token') || '';
+
+  let prompted = false;
+  const authorize = () => {
+    if (prompted) {
+      return;
+    }
+    prompted = true;
+
+    const entered = window.prompt('Admin token');
+    if (entered) {
+      sessionStorage.setItem('avenues-token', entered);
+      window.location.reload();
+    }
+  };
+
+  const withToken = (url) => {
+    if (!token) {
+      return url;
+    }
+    return url + (url.includes('?') ? '&' : '?') + 'token=' + encodeURIComponent(token);
+  };
+
+  const el = (tag, attrs, ...children) => {
+    const e = document.createElement(tag);
+    Object.entries(attrs || {}).forEach(([k, v]) => {
+      if (k.startsWith('on')) {
+        e.addEventListener(k.slice(2), v);
+      } else {
+        e.setAttribute(k, v);
+      }
+    });
+    children.forEach((c) => e.append(c === undefined || c === null ? '' : c));
+    return e;
+  };
+
+  const json = (url, opts) => fetch(url, Object.assign({}, opts, {
+    headers: token ? { Authorization: 'Bearer ' + token } : {},
+  })).then((resp) => {
+    if (resp.status === 401) {
+      authorize();
+    }
+    if (!resp.ok) {
+      throw new Error(url + ': ' + resp.status);
+    }
+    return resp.status === 204 ? null : resp.json();
+  });
+
+  const renderRoutes = (routes, faults) => {
+    const down = new Set(faults.filter((f) => f.route && !f.backend).map((f) => f.route));
+    const body = document.querySelector('#routes tbody');
+
+    body.replaceChildren(...routes.map((r) => {
+      const active = down.has(r.prefix);
+      const toggle = el('button', {
+        type: 'button',
+        class: 'fault' + (active ? ' active' : ''),
+        onclick: () => toggleFault(r.prefix, active),
+      }, active ? 'Down' : 'Up');
+
+      return el('tr', {},
+        el('td', {}, r.prefix),
+        el('td', {}, r.type),
+        el('td', {}, r.source),
+        el('td', { class: 'backends' }, (r.backends || []).join('\n')),
+        el('td', {}, r.index === undefined ? '' : String(r.index)),
+        el('td', {}, String(r.hits)),
+        el('td', { class: 'error' }, r.last_error ? r.last_error.message + ' (' + new Date(r.last_error.at).toLocaleTimeString() + ')' : ''),
+        el('td', {}, toggle));
+    }));
+  };
+
+  const refreshRoutes = () => Promise.all([json(endpoints.routes), json(endpoints.faults)])
+    .then(([routes, faults]) => renderRoutes(routes.routes, faults || []))
+    .catch((err) => console.error(err));
+
+  const toggleFault = (route, active) => {
+    const req = active
+      ? json(endpoints.faults + '?route=' + encodeURIComponent(route), { method: 'DELETE' })
+      : json(endpoints.faults, { method: 'POST', body: JSON.stringify({ route: route, down: true }) });
+
+    req.then(refreshRoutes).catch((err) => console.error(err));
+  };
+
+  const matchesFilter = (r) => {
+    const filter = document.getElementById('filter').value.trim().toLowerCase();
+    if (!filter) {
+      return true;
+    }
+
+    return [r.path, r.route, r.backend, String(r.status), r.method]
+      .some((v) => (v || '').toLowerCase().includes(filter));
+  };
+
+  const detail = (r) => {
+    const headers = Object.entries(r.headers || {})
+      .map(([k, vs]) => k + ': ' + vs.join(', '))
+      .join('\n');
+
+    let text = r.method + ' ' + r.path + (r.query ? '?' + r.query : '') + '\n\n' + headers;
+    if (r.body) {
+      text += '\n\n' + r.body + (r.truncated ? '\n[truncated]' : '');
+    }
+
+    return el('tr', { class: 'detail' }, el('td', { colspan: 7 }, el('pre', {}, text)));
+  };
+
+  const renderRequests = () => {
+    const body = document.querySelector('#requests tbody');
+    const rows = [];
+
+    requests.filter(matchesFilter).forEach((r) => {
+      rows.push(el('tr', {
+        class: 'request',
+        onclick: () => {
+          if (expanded.has(r.id)) {
+            expanded.delete(r.id);
+          } else {
+            expanded.add(r.id);
+          }
+          renderRequests();
+        },
+      },
+      el('td', {}, new Date(r.time).toLocaleTimeString()),
+      el('td', {}, r.method),
+      el('td', { class: 'path' }, r.path),
+      el('td', {}, r.route || ''),
+      el('td', { class: 'backend' }, r.backend || ''),
+      el('td', { class: 'code-' + String(r.status)[0] }, String(r.status)),
+      el('td', {}, r.duration_ms.toFixed(1) + 'ms')));
+
+      if (expanded.has(r.id)) {
+        rows.push(detail(r));
+      }
+    });
+
+    body.replaceChildren(...rows);
+  };
+
+  const addRequest = (r) => {
+    if (requests.some((e) => e.id === r.id)) {
+      return;
+    }
+
+    requests.unshift(r);
+    requests.splice(maxRequests);
+    renderRequests();
+  };
+
+  const connect = () => {
+    const status = document.getElementById('status');
+    const source = new EventSource(withToken(endpoints.stream));
+
+    source.onopen = () => {
+      status.textContent = 'live';
+      status.className = 'status live';
+    };
+
+    source.onerror = () => {
+      status.textContent = 'reconnecting';
+      status.className = 'status';
+    };
+
+    source.addEventListener('request', (ev) => {
+      addRequest(JSON.parse(ev.data));
+      refreshRoutes();
+    });
+  };
+
+  document.getElementById('reset').addEventListener('click', () => {
+    json(endpoints.reset, { method: 'POST' }).catch(() => {}).then(refreshRoutes);
+  });
+
+  document.getElementById('filter').addEventListener('input', renderRequests);
+
+  json(endpoints.requests).then((body) => {
+    body.requests.forEach(addRequest);
+  }).catch((err) => console.error(err));
+
+  refreshRoutes();
+  setInterval(refreshRoutes, 5000);
+  connect();
+})();
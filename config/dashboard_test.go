@@ -0,0 +1,80 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestDashboard(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Web dashboard", func() {
+		var admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(`
+routes: {}
+stream: /custom/stream
+`))
+			Expect(err).To(BeNil())
+
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			admin.Close()
+		})
+
+		get := func(path string) (*http.Response, string) {
+			client := &http.Client{
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			resp, err := client.Get(admin.URL + path)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).To(BeNil())
+
+			return resp, string(b)
+		}
+
+		g.It("should serve the page with the configured endpoints", func() {
+			resp, _ := get("/avenues/ui")
+			Expect(resp.StatusCode).To(Equal(http.StatusMovedPermanently))
+			Expect(resp.Header.Get("Location")).To(Equal("/avenues/ui/"))
+
+			resp, body := get("/avenues/ui/")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+			Expect(body).To(ContainSubstring("<title>Avenues</title>"))
+			Expect(body).To(ContainSubstring(`"stream":"/custom/stream"`))
+			Expect(body).To(ContainSubstring(`"routes":"/avenues/routes"`))
+		})
+
+		g.It("should serve the page's assets", func() {
+			resp, body := get("/avenues/ui/app.js")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(ContainSubstring("javascript"))
+			Expect(body).To(ContainSubstring("EventSource"))
+
+			resp, _ = get("/avenues/ui/style.css")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(ContainSubstring("text/css"))
+
+			resp, _ = get("/avenues/ui/missing.js")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+}
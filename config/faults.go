@@ -0,0 +1,268 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/gomicro/ledger"
+)
+
+// Fault represents a failure injected into a route or backend while Avenues
+// is running. A fault with both a route and a backend only applies when both
+// match the request.
+type Fault struct {
+	Route   string `json:"route,omitempty"`
+	Backend string `json:"backend,omitempty"`
+	Down    bool   `json:"down,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	Refuse  bool   `json:"refuse,omitempty"`
+	Latency string `json:"latency,omitempty"`
+	latency time.Duration
+	host    string
+}
+
+func (f *Fault) init() error {
+	if f.Route == "" && f.Backend == "" {
+		return fmt.Errorf("fault requires a route or backend")
+	}
+
+	if f.Backend != "" {
+		u, err := url.Parse(f.Backend)
+		if err != nil {
+			return fmt.Errorf("failed to parse backend: %v", err.Error())
+		}
+
+		f.host = u.Host
+		if f.host == "" {
+			f.host = f.Backend
+		}
+	}
+
+	if f.Latency != "" {
+		d, err := time.ParseDuration(f.Latency)
+		if err != nil {
+			return fmt.Errorf("failed to parse latency: %v", err.Error())
+		}
+
+		f.latency = d
+	}
+
+	if f.Down && f.Status == 0 {
+		f.Status = http.StatusServiceUnavailable
+	}
+
+	return nil
+}
+
+func (f *Fault) key() string {
+	return fmt.Sprintf("%v|%v", f.Route, f.Backend)
+}
+
+// matches reports whether the fault applies to a request for the route. With
+// no backend only faults without one match, and with a backend only faults
+// on that backend, so each fault is applied once: route faults before the
+// route serves the request, and backend faults before it is proxied.
+func (f *Fault) matches(prefix string, u *url.URL) bool {
+	if f.Route != "" && f.Route != prefix {
+		return false
+	}
+
+	if u == nil {
+		return f.host == ""
+	}
+
+	return f.host != "" && f.host == u.Host
+}
+
+// apply injects the fault into the response, reporting whether the request
+// has been answered and must not be proxied, and whether it was answered by
+// refusing the connection.
+func (f *Fault) apply(w http.ResponseWriter) (bool, bool) {
+	if f.latency > 0 {
+		time.Sleep(f.latency)
+	}
+
+	if f.Refuse {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true, false
+		}
+
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			log.Errorf("failed to hijack connection: %v", err.Error())
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true, false
+		}
+
+		if tc, ok := conn.(*net.TCPConn); ok {
+			_ = tc.SetLinger(0)
+		}
+
+		conn.Close()
+		return true, true
+	}
+
+	if f.Status != 0 {
+		w.WriteHeader(f.Status)
+		return true, false
+	}
+
+	return false, false
+}
+
+// interrupt applies the fault to a call Avenues makes to a backend on behalf
+// of a request, such as its share of a split batch, returning an error when
+// the call must fail.
+func (f *Fault) interrupt() error {
+	if f.latency > 0 {
+		time.Sleep(f.latency)
+	}
+
+	if f.Refuse {
+		return fmt.Errorf("connection refused by fault")
+	}
+
+	if f.Status != 0 {
+		return fmt.Errorf("answered %v by fault", f.Status)
+	}
+
+	return nil
+}
+
+// injectFault applies any fault on the route, or on the backend when one is
+// given, to the request, reporting whether it has been answered
+func (f *File) injectFault(w http.ResponseWriter, req *http.Request, prefix string, u *url.URL, entry *JournalEntry) bool {
+	fault, ok := f.faults.find(prefix, u)
+	if !ok {
+		return false
+	}
+
+	answered, refused := fault.apply(w)
+	if !answered {
+		return false
+	}
+
+	entry.Refused = refused
+	log.Infof("fault injected for '%v'", req.URL)
+
+	return true
+}
+
+type faultSet struct {
+	sync.RWMutex
+	faults map[string]*Fault
+}
+
+func newFaultSet() *faultSet {
+	return &faultSet{
+		faults: make(map[string]*Fault),
+	}
+}
+
+func (s *faultSet) find(prefix string, u *url.URL) (*Fault, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, f := range s.faults {
+		if f.matches(prefix, u) {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *faultSet) list() []*Fault {
+	s.RLock()
+	defer s.RUnlock()
+
+	faults := make([]*Fault, 0, len(s.faults))
+	for _, f := range s.faults {
+		faults = append(faults, f)
+	}
+
+	sort.Slice(faults, func(i, j int) bool {
+		return faults[i].key() < faults[j].key()
+	})
+
+	return faults
+}
+
+func (s *faultSet) set(f *Fault) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.faults[f.key()] = f
+}
+
+func (s *faultSet) remove(route, backend string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.faults, (&Fault{Route: route, Backend: backend}).key())
+}
+
+func (s *faultSet) clear() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.faults = make(map[string]*Fault)
+}
+
+func (s *faultSet) replace(faults []*Fault) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.faults = make(map[string]*Fault, len(faults))
+	for _, f := range faults {
+		s.faults[f.key()] = f
+	}
+}
+
+func (f *File) handleFaults(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, f.faults.list())
+	case http.MethodPost, http.MethodPut:
+		var fault Fault
+		err := json.NewDecoder(req.Body).Decode(&fault)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode fault: %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err = fault.init()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.faults.set(&fault)
+		log.Infof("fault set for route '%v' backend '%v'", fault.Route, fault.Backend)
+
+		writeJSON(w, http.StatusOK, &fault)
+	case http.MethodDelete:
+		route := req.URL.Query().Get("route")
+		backend := req.URL.Query().Get("backend")
+
+		if route == "" && backend == "" {
+			f.faults.clear()
+			log.Info("all faults cleared")
+		} else {
+			f.faults.remove(route, backend)
+			log.Infof("fault cleared for route '%v' backend '%v'", route, backend)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
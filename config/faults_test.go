@@ -0,0 +1,195 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestFaults(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	g.Describe("Faults", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/foo:
+    backend: %v
+  /v1/bar:
+    backend: %v
+`, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		setFault := func(body string) {
+			res, err := http.Post(admin.URL+"/avenues/faults", "application/json", bytes.NewBufferString(body))
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		}
+
+		g.It("should mark a route as down", func() {
+			setFault(`{"route": "/v1/foo", "down": true}`)
+
+			res, err := http.Get(server.URL + "/v1/foo")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+
+			res, err = http.Get(server.URL + "/v1/bar")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		g.It("should mark a backend as down", func() {
+			setFault(fmt.Sprintf(`{"backend": "%v", "down": true}`, backend.URL))
+
+			res, err := http.Get(server.URL + "/v1/bar")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		})
+
+		g.It("should refuse connections", func() {
+			setFault(`{"route": "/v1/foo", "refuse": true}`)
+
+			_, err := http.Get(server.URL + "/v1/foo")
+			Expect(err).NotTo(BeNil())
+
+			var journal struct {
+				Requests []*config.JournalEntry `json:"requests"`
+			}
+			Eventually(func() []*config.JournalEntry {
+				res, err := http.Get(admin.URL + "/avenues/requests?route=/v1/foo")
+				Expect(err).To(BeNil())
+				defer res.Body.Close()
+
+				Expect(json.NewDecoder(res.Body).Decode(&journal)).To(BeNil())
+				return journal.Requests
+			}).ShouldNot(BeEmpty())
+
+			Expect(journal.Requests[0].Refused).To(BeTrue())
+			Expect(journal.Requests[0].Status).To(Equal(0))
+		})
+
+		g.It("should add latency", func() {
+			setFault(`{"route": "/v1/foo", "latency": "200ms"}`)
+
+			start := time.Now()
+			res, err := http.Get(server.URL + "/v1/foo")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		})
+
+		g.It("should clear all faults", func() {
+			setFault(`{"route": "/v1/foo", "down": true}`)
+
+			req, err := http.NewRequest(http.MethodDelete, admin.URL+"/avenues/faults", nil)
+			Expect(err).To(BeNil())
+
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+
+			res, err = http.Get(server.URL + "/v1/foo")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		g.It("should mark routes down that answer without a backend", func() {
+			dir, err := ioutil.TempDir("", "faults")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "replay.yaml"), []byte(`
+interactions:
+  - route: /v1/replay
+    request:
+      method: GET
+      path: /v1/replay
+    response:
+      status: 200
+      body: replayed
+`), 0644)).To(BeNil())
+
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /app:
+    type: static_dir
+    dir: %v
+  /v1/replay:
+    type: replay
+    cassette: %v
+`, dir, filepath.Join(dir, "replay.yaml"))))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			admin := httptest.NewServer(c.AdminHandler())
+			defer admin.Close()
+
+			for _, p := range []string{"/app/", "/v1/replay"} {
+				res, err := http.Get(server.URL + p)
+				Expect(err).To(BeNil())
+				res.Body.Close()
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+				res, err = http.Post(admin.URL+"/avenues/faults", "application/json", bytes.NewBufferString(fmt.Sprintf(`{"route": %q, "down": true}`, strings.TrimSuffix(p, "/"))))
+				Expect(err).To(BeNil())
+				res.Body.Close()
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+				res, err = http.Get(server.URL + p)
+				Expect(err).To(BeNil())
+				res.Body.Close()
+				Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			}
+		})
+
+		g.It("should reject a fault without a target", func() {
+			res, err := http.Post(admin.URL+"/avenues/faults", "application/json", bytes.NewBufferString(`{"down": true}`))
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+}
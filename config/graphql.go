@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gomicro/avenues/graphql"
+	log "github.com/gomicro/ledger"
+)
+
+// GraphQLRule represents where to send GraphQL operations that match it. Every
+// populated matcher must match for the rule to apply.
+type GraphQLRule struct {
+	Operation string    `yaml:"operation,omitempty"`
+	Type      string    `yaml:"type,omitempty"`
+	Field     string    `yaml:"field,omitempty"`
+	Backend   string    `yaml:"backend,omitempty"`
+	Response  *Response `yaml:"response,omitempty"`
+}
+
+type graphQLRequest struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName"`
+}
+
+func loadGraphQL(route *Route) error {
+	if len(route.Operations) == 0 && route.Backend == "" {
+		return fmt.Errorf("graphql route requires operations or backend directive")
+	}
+
+	for _, rule := range route.Operations {
+		if rule.Operation == "" && rule.Type == "" && rule.Field == "" {
+			return fmt.Errorf("graphql operation requires operation, type, or field directive")
+		}
+
+		rule.Type = strings.ToLower(rule.Type)
+		switch rule.Type {
+		case "", graphql.Query, graphql.Mutation, graphql.Subscription:
+		default:
+			return fmt.Errorf("unknown graphql operation type: %v", rule.Type)
+		}
+
+		if (rule.Backend == "") == (rule.Response == nil) {
+			return fmt.Errorf("graphql operation requires one of backend or response directive")
+		}
+
+		if rule.Backend != "" {
+			err := checkBackend(rule.Backend)
+			if err != nil {
+				return fmt.Errorf("invalid graphql backend: %v", err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// routeGraphQL finds the rule matching the GraphQL operation in the request.
+// Rules with a canned response are answered directly and true is returned.
+// Otherwise the backend the request should be proxied to is returned, which
+// is empty when the route's own backend applies.
+func routeGraphQL(w http.ResponseWriter, req *http.Request, route *Route) (string, bool) {
+	op, err := graphQLOperation(req)
+	if err != nil {
+		if route.Backend != "" {
+			log.Debugf("proxying unreadable graphql request: %v", err.Error())
+			return "", false
+		}
+
+		log.Warnf("failed to read graphql request: %v", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", true
+	}
+
+	for _, rule := range route.Operations {
+		if !rule.matches(op) {
+			continue
+		}
+
+		if rule.Response != nil {
+			rule.Response.write(w)
+			log.Infof("answered graphql %v '%v' with canned response", op.Type, op.Name)
+			return "", true
+		}
+
+		return rule.Backend, false
+	}
+
+	if route.Backend == "" {
+		log.Warnf("no graphql rule for %v '%v'", op.Type, op.Name)
+		w.WriteHeader(http.StatusNotFound)
+		return "", true
+	}
+
+	return "", false
+}
+
+func (rule *GraphQLRule) matches(op *graphql.Operation) bool {
+	if rule.Operation != "" && rule.Operation != op.Name {
+		return false
+	}
+
+	if rule.Type != "" && rule.Type != op.Type {
+		return false
+	}
+
+	if rule.Field != "" && !op.HasField(rule.Field) {
+		return false
+	}
+
+	return true
+}
+
+// graphQLOperation reads the operation from a GET request's query parameters
+// or from a POST request's body, sent as either JSON or a bare document.
+func graphQLOperation(req *http.Request) (*graphql.Operation, error) {
+	if req.Method == http.MethodGet {
+		q := req.URL.Query()
+		return graphql.Parse(q.Get("query"), q.Get("operationName"))
+	}
+
+	b, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/graphql") {
+		return graphql.Parse(string(b), req.URL.Query().Get("operationName"))
+	}
+
+	var gr graphQLRequest
+	err = json.Unmarshal(b, &gr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal graphql request: %v", err.Error())
+	}
+
+	return graphql.Parse(gr.Query, gr.OperationName)
+}
@@ -0,0 +1,171 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestGraphQL(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			w.Write([]byte(name + ":" + string(b)))
+		}))
+	}
+
+	users := newBackend("users")
+	defer users.Close()
+
+	orders := newBackend("orders")
+	defer orders.Close()
+
+	gateway := newBackend("gateway")
+	defer gateway.Close()
+
+	g.Describe("GraphQL routes", func() {
+		var server *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /graphql:
+    type: graphql
+    backend: %v
+    operations:
+      - operation: Maintenance
+        response:
+          status: 503
+          headers:
+            Content-Type: application/json
+          body: '{"errors":[{"message":"down for maintenance"}]}'
+      - type: mutation
+        field: placeOrder
+        backend: %v
+      - field: user
+        backend: %v
+  /strict/:
+    type: graphql
+    operations:
+      - type: subscription
+        response:
+          body: '{"data":null}'
+`, gateway.URL, orders.URL, users.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+		})
+
+		post := func(path, body string) (int, string, http.Header) {
+			resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).To(BeNil())
+
+			return resp.StatusCode, string(b), resp.Header
+		}
+
+		g.It("should route operations by their root fields", func() {
+			body := `{"query":"query Profile { user(id: 1) { name } }"}`
+			status, b, _ := post("/graphql", body)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(b).To(Equal("users:" + body))
+		})
+
+		g.It("should route operations by their type", func() {
+			body := `{"query":"mutation { placeOrder(sku: \"a\") { id } }"}`
+			status, b, _ := post("/graphql", body)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(b).To(Equal("orders:" + body))
+
+			body = `{"query":"query { placeOrder { id } }"}`
+			_, b, _ = post("/graphql", body)
+			Expect(b).To(Equal("gateway:" + body))
+		})
+
+		g.It("should answer operations by name with canned responses", func() {
+			body := `{"query":"query Maintenance { user { id } } query Other { user { id } }","operationName":"Maintenance"}`
+			status, b, h := post("/graphql", body)
+			Expect(status).To(Equal(http.StatusServiceUnavailable))
+			Expect(b).To(Equal(`{"errors":[{"message":"down for maintenance"}]}`))
+			Expect(h.Get("Content-Type")).To(Equal("application/json"))
+		})
+
+		g.It("should read operations from query parameters and bare documents", func() {
+			resp, err := http.Get(server.URL + "/graphql?query=" + url.QueryEscape("{ user { id } }"))
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			Expect(string(b)).To(Equal("users:"))
+
+			resp, err = http.Post(server.URL+"/graphql", "application/graphql", strings.NewReader("mutation { placeOrder { id } }"))
+			Expect(err).To(BeNil())
+			b, _ = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			Expect(string(b)).To(Equal("orders:mutation { placeOrder { id } }"))
+		})
+
+		g.It("should fall back to the route backend for unreadable requests", func() {
+			status, b, _ := post("/graphql", `not json`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(b).To(Equal("gateway:not json"))
+		})
+
+		g.It("should reject requests with no rule or backend", func() {
+			status, _, _ := post("/strict/", `{"query":"subscription { ticks }"}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _, _ = post("/strict/", `{"query":"{ ticks }"}`)
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			status, _, _ = post("/strict/", `{"query":"{ ticks "}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+		})
+
+		g.It("should reject invalid rules", func() {
+			_, err := config.Parse([]byte(`
+routes:
+  /graphql:
+    type: graphql
+    operations:
+      - type: query
+`))
+			Expect(err).NotTo(BeNil())
+
+			_, err = config.Parse([]byte(`
+routes:
+  /graphql:
+    type: graphql
+    operations:
+      - type: fetch
+        backend: http://localhost
+`))
+			Expect(err).NotTo(BeNil())
+
+			_, err = config.Parse([]byte(`
+routes:
+  /graphql:
+    type: graphql
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const harVersion = "1.2"
+
+type har struct {
+	Log *harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator *harCreator `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time    `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         *harRequest  `json:"request"`
+	Response        *harResponse `json:"response"`
+	Cache           struct{}     `json:"cache"`
+	Timings         *harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*harNameValue `json:"cookies"`
+	Headers     []*harNameValue `json:"headers"`
+	QueryString []*harNameValue `json:"queryString"`
+	PostData    *harPostData    `json:"postData,omitempty"`
+	HeadersSize int             `json:"headersSize"`
+	BodySize    int             `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int             `json:"status"`
+	StatusText  string          `json:"statusText"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*harNameValue `json:"cookies"`
+	Headers     []*harNameValue `json:"headers"`
+	Content     *harContent     `json:"content"`
+	RedirectURL string          `json:"redirectURL"`
+	HeadersSize int             `json:"headersSize"`
+	BodySize    int             `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// LoadHAR reads an HTTP Archive from the given file and converts its entries
+// into a cassette.
+func LoadHAR(path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read har: %v", err.Error())
+	}
+
+	var h har
+	err = json.Unmarshal(b, &h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal har: %v", err.Error())
+	}
+
+	if h.Log == nil {
+		return nil, fmt.Errorf("har is missing log")
+	}
+
+	c := &Cassette{}
+
+	for _, e := range h.Log.Entries {
+		if e.Request == nil || e.Response == nil {
+			continue
+		}
+
+		in, err := e.interaction()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Interactions = append(c.Interactions, in)
+	}
+
+	return c, nil
+}
+
+func (e *harEntry) interaction() (*Interaction, error) {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse har request url: %v", err.Error())
+	}
+
+	req := &RecordedRequest{
+		Method:  e.Request.Method,
+		Host:    u.Host,
+		Path:    u.Path,
+		Query:   u.RawQuery,
+		Headers: harHeaders(e.Request.Headers),
+	}
+
+	if e.Request.PostData != nil {
+		req.Body = e.Request.PostData.Text
+	}
+
+	resp := &RecordedResponse{
+		Status:  e.Response.Status,
+		Headers: harHeaders(e.Response.Headers),
+	}
+
+	if e.Response.Content != nil {
+		resp.Body = e.Response.Content.Text
+
+		if e.Response.Content.Encoding == "base64" {
+			b, err := base64.StdEncoding.DecodeString(resp.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode har response content: %v", err.Error())
+			}
+
+			resp.Body = string(b)
+		}
+	}
+
+	// Browsers record the decoded body, so the original encoding and length
+	// no longer apply.
+	resp.Headers.Del("Content-Encoding")
+	resp.Headers.Del("Content-Length")
+
+	return &Interaction{
+		RecordedAt: e.StartedDateTime,
+		Request:    req,
+		Response:   resp,
+	}, nil
+}
+
+func newHAR(interactions []*Interaction) *har {
+	h := &har{
+		Log: &harLog{
+			Version: harVersion,
+			Creator: &harCreator{Name: "avenues", Version: harVersion},
+			Entries: make([]*harEntry, 0, len(interactions)),
+		},
+	}
+
+	for _, in := range interactions {
+		h.Log.Entries = append(h.Log.Entries, newHAREntry(in))
+	}
+
+	return h
+}
+
+func newHAREntry(in *Interaction) *harEntry {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     in.Request.Host,
+		Path:     in.Request.Path,
+		RawQuery: in.Request.Query,
+	}
+
+	req := &harRequest{
+		Method:      in.Request.Method,
+		URL:         u.String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []*harNameValue{},
+		Headers:     harNameValues(in.Request.Headers),
+		QueryString: []*harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(in.Request.Body),
+	}
+
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			req.QueryString = append(req.QueryString, &harNameValue{Name: k, Value: v})
+		}
+	}
+
+	if in.Request.Body != "" {
+		req.PostData = &harPostData{
+			MimeType: in.Request.Headers.Get("Content-Type"),
+			Text:     in.Request.Body,
+		}
+	}
+
+	content := &harContent{
+		Size:     len(in.Response.Body),
+		MimeType: in.Response.Headers.Get("Content-Type"),
+		Text:     in.Response.Body,
+	}
+
+	if !utf8.ValidString(content.Text) {
+		content.Text = base64.StdEncoding.EncodeToString([]byte(in.Response.Body))
+		content.Encoding = "base64"
+	}
+
+	return &harEntry{
+		StartedDateTime: in.RecordedAt,
+		Request:         req,
+		Response: &harResponse{
+			Status:      in.Response.Status,
+			StatusText:  http.StatusText(in.Response.Status),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []*harNameValue{},
+			Headers:     harNameValues(in.Response.Headers),
+			Content:     content,
+			RedirectURL: in.Response.Headers.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(in.Response.Body),
+		},
+		Timings: &harTimings{},
+	}
+}
+
+func harHeaders(nvs []*harNameValue) http.Header {
+	h := http.Header{}
+	for _, nv := range nvs {
+		// HTTP/2 pseudo headers are not real headers
+		if strings.HasPrefix(nv.Name, ":") {
+			continue
+		}
+
+		h.Add(nv.Name, nv.Value)
+	}
+
+	return h
+}
+
+func harNameValues(h http.Header) []*harNameValue {
+	nvs := []*harNameValue{}
+	for k, vs := range h {
+		for _, v := range vs {
+			nvs = append(nvs, &harNameValue{Name: k, Value: v})
+		}
+	}
+
+	return nvs
+}
+
+func (f *File) handleHAR(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	route := req.URL.Query().Get("route")
+
+	var interactions []*Interaction
+	for _, in := range f.recorder.capturedInteractions() {
+		if route != "" && in.Route != route {
+			continue
+		}
+
+		interactions = append(interactions, in)
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="avenues.har"`)
+	writeJSON(w, http.StatusOK, newHAR(interactions))
+}
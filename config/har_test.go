@@ -0,0 +1,286 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+const harFixture = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "browser", "version": "1"},
+    "entries": [
+      {
+        "startedDateTime": "2021-06-01T12:00:00Z",
+        "time": 12,
+        "request": {
+          "method": "GET",
+          "url": "https://api.example.com/v1/foo/users?page=2",
+          "httpVersion": "HTTP/2",
+          "headers": [{"name": ":authority", "value": "api.example.com"}],
+          "queryString": [{"name": "page", "value": "2"}],
+          "cookies": [],
+          "headersSize": -1,
+          "bodySize": 0
+        },
+        "response": {
+          "status": 200,
+          "statusText": "OK",
+          "httpVersion": "HTTP/2",
+          "headers": [
+            {"name": "content-type", "value": "application/json"},
+            {"name": "content-encoding", "value": "gzip"}
+          ],
+          "cookies": [],
+          "content": {"size": 11, "mimeType": "application/json", "text": "eyJpZCI6IDF9", "encoding": "base64"},
+          "redirectURL": "",
+          "headersSize": -1,
+          "bodySize": 11
+        },
+        "cache": {},
+        "timings": {"send": 0, "wait": 10, "receive": 2}
+      }
+    ]
+  }
+}`
+
+func TestHAR(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer backend.Close()
+
+	g.Describe("HAR", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			d, err := ioutil.TempDir("", "har")
+			Expect(err).To(BeNil())
+			dir = d
+		})
+
+		g.AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		g.It("should export captured traffic", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+record:
+  dir: %v
+routes:
+  /v1/foo:
+    backend: %v
+    record: true
+`, dir, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			admin := httptest.NewServer(c.AdminHandler())
+			defer admin.Close()
+
+			res, err := http.Get(server.URL + "/v1/foo/users?page=1")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			res, err = http.Get(admin.URL + "/avenues/har")
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			var h struct {
+				Log struct {
+					Version string `json:"version"`
+					Entries []struct {
+						Request struct {
+							Method string `json:"method"`
+							URL    string `json:"url"`
+						} `json:"request"`
+						Response struct {
+							Status  int `json:"status"`
+							Content struct {
+								Text string `json:"text"`
+							} `json:"content"`
+						} `json:"response"`
+					} `json:"entries"`
+				} `json:"log"`
+			}
+			err = json.NewDecoder(res.Body).Decode(&h)
+			Expect(err).To(BeNil())
+
+			Expect(h.Log.Version).To(Equal("1.2"))
+			Expect(len(h.Log.Entries)).To(Equal(1))
+			Expect(h.Log.Entries[0].Request.Method).To(Equal(http.MethodGet))
+			Expect(h.Log.Entries[0].Request.URL).To(HaveSuffix("/v1/foo/users?page=1"))
+			Expect(h.Log.Entries[0].Response.Status).To(Equal(http.StatusOK))
+			Expect(h.Log.Entries[0].Response.Content.Text).To(Equal("hello"))
+		})
+
+		g.It("should export traffic captured without recording cassettes", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+record:
+  dir: %v
+routes:
+  /v1/foo:
+    backend: %v
+    capture: true
+  /v1/bar:
+    backend: %v
+`, dir, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			admin := httptest.NewServer(c.AdminHandler())
+			defer admin.Close()
+
+			for _, p := range []string{"/v1/foo/users", "/v1/bar/users"} {
+				res, err := http.Get(server.URL + p)
+				Expect(err).To(BeNil())
+				res.Body.Close()
+			}
+
+			res, err := http.Get(admin.URL + "/avenues/har")
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			var h struct {
+				Log struct {
+					Entries []struct {
+						Request struct {
+							URL string `json:"url"`
+						} `json:"request"`
+					} `json:"entries"`
+				} `json:"log"`
+			}
+			Expect(json.NewDecoder(res.Body).Decode(&h)).To(BeNil())
+			Expect(h.Log.Entries).To(HaveLen(1))
+			Expect(h.Log.Entries[0].Request.URL).To(HaveSuffix("/v1/foo/users"))
+
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).To(BeNil())
+			Expect(files).To(BeEmpty())
+		})
+
+		g.It("should keep only the most recent traffic until a reset", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+record:
+  dir: %v
+journal:
+  size: 2
+routes:
+  /v1/foo:
+    backend: %v
+    record: true
+`, dir, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			admin := httptest.NewServer(c.AdminHandler())
+			defer admin.Close()
+
+			for _, p := range []string{"/v1/foo/1", "/v1/foo/2", "/v1/foo/3"} {
+				res, err := http.Get(server.URL + p)
+				Expect(err).To(BeNil())
+				res.Body.Close()
+			}
+
+			entries := func() []string {
+				res, err := http.Get(admin.URL + "/avenues/har")
+				Expect(err).To(BeNil())
+				defer res.Body.Close()
+
+				var h struct {
+					Log struct {
+						Entries []struct {
+							Request struct {
+								URL string `json:"url"`
+							} `json:"request"`
+						} `json:"entries"`
+					} `json:"log"`
+				}
+				Expect(json.NewDecoder(res.Body).Decode(&h)).To(BeNil())
+
+				var urls []string
+				for _, e := range h.Log.Entries {
+					urls = append(urls, e.Request.URL[strings.Index(e.Request.URL, "/v1"):])
+				}
+
+				return urls
+			}
+
+			Expect(entries()).To(Equal([]string{"/v1/foo/2", "/v1/foo/3"}))
+
+			res, err := http.Post(admin.URL+"/avenues/reset", "", nil)
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(entries()).To(BeEmpty())
+		})
+
+		g.It("should load a har as a cassette", func() {
+			p := filepath.Join(dir, "fixture.har")
+			err := ioutil.WriteFile(p, []byte(harFixture), 0644)
+			Expect(err).To(BeNil())
+
+			cas, err := config.LoadHAR(p)
+			Expect(err).To(BeNil())
+			Expect(len(cas.Interactions)).To(Equal(1))
+
+			in := cas.Interactions[0]
+			Expect(in.Request.Path).To(Equal("/v1/foo/users"))
+			Expect(in.Request.Query).To(Equal("page=2"))
+			Expect(in.Request.Headers).To(BeEmpty())
+			Expect(in.Response.Body).To(Equal(`{"id": 1}`))
+			Expect(in.Response.Headers.Get("Content-Encoding")).To(BeEmpty())
+		})
+
+		g.It("should serve mock responses from a har", func() {
+			p := filepath.Join(dir, "fixture.har")
+			err := ioutil.WriteFile(p, []byte(harFixture), 0644)
+			Expect(err).To(BeNil())
+
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/foo:
+    type: replay
+    har: %v
+`, p)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			res, err := http.Get(server.URL + "/v1/foo/users?page=2")
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(string(b)).To(Equal(`{"id": 1}`))
+		})
+	})
+}
@@ -0,0 +1,332 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/gomicro/ledger"
+)
+
+const (
+	defaultJournalSize = 1000
+	defaultJournalBody = 1024
+
+	subscriberBuffer = 64
+)
+
+// Journal represents how much of the traffic through Avenues is remembered.
+// Size is the number of requests kept and Body the number of bytes of each
+// request body.
+type Journal struct {
+	Size int `yaml:"size,omitempty"`
+	Body int `yaml:"body,omitempty"`
+}
+
+// JournalEntry represents a request handled by Avenues
+type JournalEntry struct {
+	ID        int64       `json:"id"`
+	Time      time.Time   `json:"time"`
+	Method    string      `json:"method"`
+	Path      string      `json:"path"`
+	Query     string      `json:"query,omitempty"`
+	Headers   http.Header `json:"headers,omitempty"`
+	Body      string      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+	Refused   bool        `json:"refused,omitempty"`
+	Abandoned bool        `json:"abandoned,omitempty"`
+	Route     string      `json:"route,omitempty"`
+	Backend   string      `json:"backend,omitempty"`
+	Status    int         `json:"status"`
+	Duration  float64     `json:"duration_ms"`
+}
+
+// RequestMatcher represents the journal entries to select. Unset fields match
+// every entry. Paths are patterns where segments in braces match any segment,
+// and statuses are either exact or a class such as 5xx.
+type RequestMatcher struct {
+	Route  string    `json:"route,omitempty"`
+	Path   string    `json:"path,omitempty"`
+	Method string    `json:"method,omitempty"`
+	Status string    `json:"status,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+	Until  time.Time `json:"until,omitempty"`
+}
+
+// requestJournal keeps the most recent requests in a ring buffer
+type requestJournal struct {
+	sync.RWMutex
+	conf    *Journal
+	entries []*JournalEntry
+	start   int
+	count   int
+	nextID  int64
+	changed chan struct{}
+	subs    map[chan *JournalEntry]struct{}
+}
+
+func newRequestJournal(conf *Journal) (*requestJournal, error) {
+	if conf.Size < 0 || conf.Body < 0 {
+		return nil, fmt.Errorf("journal size and body must not be negative")
+	}
+
+	if conf.Size == 0 {
+		conf.Size = defaultJournalSize
+	}
+
+	if conf.Body == 0 {
+		conf.Body = defaultJournalBody
+	}
+
+	return &requestJournal{
+		conf:    conf,
+		entries: make([]*JournalEntry, conf.Size),
+		changed: make(chan struct{}),
+		subs:    make(map[chan *JournalEntry]struct{}),
+	}, nil
+}
+
+// begin starts an entry for the request, keeping an excerpt of its body
+// without consuming it.
+func (j *requestJournal) begin(req *http.Request) *JournalEntry {
+	e := &JournalEntry{
+		Time:    time.Now().UTC(),
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Query:   req.URL.RawQuery,
+		Headers: req.Header.Clone(),
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		b, _ := ioutil.ReadAll(io.LimitReader(req.Body, int64(j.conf.Body)+1))
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), req.Body), req.Body}
+
+		if len(b) > j.conf.Body {
+			b = b[:j.conf.Body]
+			e.Truncated = true
+		}
+
+		e.Body = string(b)
+	}
+
+	return e
+}
+
+// finish completes the entry with the status the request was answered with
+// and adds it to the journal, dropping the oldest entry when full. Refused
+// requests, whose connections were closed unanswered, and abandoned requests,
+// whose clients went away while paused, keep a status of 0.
+func (j *requestJournal) finish(e *JournalEntry, status int) {
+	if status == 0 && !e.Refused && !e.Abandoned {
+		status = http.StatusOK
+	}
+
+	e.Status = status
+	e.Duration = float64(time.Since(e.Time)) / float64(time.Millisecond)
+
+	j.Lock()
+	defer j.Unlock()
+
+	j.nextID++
+	e.ID = j.nextID
+
+	i := (j.start + j.count) % len(j.entries)
+	j.entries[i] = e
+
+	if j.count < len(j.entries) {
+		j.count++
+	} else {
+		j.start = (j.start + 1) % len(j.entries)
+	}
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+
+	for sub := range j.subs {
+		select {
+		case sub <- e:
+		default:
+			log.Warnf("dropping journal entry %v for slow subscriber", e.ID)
+		}
+	}
+}
+
+// subscribe returns a channel receiving every entry added from now on, until
+// it is passed to unsubscribe. Entries are dropped for subscribers that fall
+// behind rather than holding up requests.
+func (j *requestJournal) subscribe() chan *JournalEntry {
+	sub := make(chan *JournalEntry, subscriberBuffer)
+
+	j.Lock()
+	j.subs[sub] = struct{}{}
+	j.Unlock()
+
+	return sub
+}
+
+func (j *requestJournal) unsubscribe(sub chan *JournalEntry) {
+	j.Lock()
+	delete(j.subs, sub)
+	j.Unlock()
+}
+
+// watch returns a channel closed the next time an entry is added
+func (j *requestJournal) watch() <-chan struct{} {
+	j.RLock()
+	defer j.RUnlock()
+
+	return j.changed
+}
+
+// find returns the entries the matcher selects, oldest first
+func (j *requestJournal) find(m *RequestMatcher) []*JournalEntry {
+	found := []*JournalEntry{}
+	for _, e := range j.all() {
+		if m.matches(e) {
+			found = append(found, e)
+		}
+	}
+
+	return found
+}
+
+// all returns every entry in the journal, oldest first
+func (j *requestJournal) all() []*JournalEntry {
+	j.RLock()
+	defer j.RUnlock()
+
+	entries := make([]*JournalEntry, j.count)
+	for n := 0; n < j.count; n++ {
+		entries[n] = j.entries[(j.start+n)%len(j.entries)]
+	}
+
+	return entries
+}
+
+func (j *requestJournal) clear() {
+	j.Lock()
+	defer j.Unlock()
+
+	j.entries = make([]*JournalEntry, len(j.entries))
+	j.start = 0
+	j.count = 0
+}
+
+func (m *RequestMatcher) validate() error {
+	if m.Status == "" {
+		return nil
+	}
+
+	if len(m.Status) == 3 && strings.HasSuffix(strings.ToLower(m.Status), "xx") && m.Status[0] >= '1' && m.Status[0] <= '5' {
+		return nil
+	}
+
+	_, err := strconv.Atoi(m.Status)
+	if err != nil {
+		return fmt.Errorf("status must be a code or class such as 5xx")
+	}
+
+	return nil
+}
+
+func (m *RequestMatcher) matches(e *JournalEntry) bool {
+	return len(m.mismatches(e)) == 0
+}
+
+// mismatches describes each way the entry fails to match
+func (m *RequestMatcher) mismatches(e *JournalEntry) []string {
+	var out []string
+
+	if m.Route != "" && m.Route != e.Route {
+		out = append(out, fmt.Sprintf("route: expected %v, got %v", m.Route, e.Route))
+	}
+
+	if m.Path != "" {
+		if _, ok := matchPattern(m.Path, e.Path); !ok {
+			out = append(out, fmt.Sprintf("path: expected %v, got %v", m.Path, e.Path))
+		}
+	}
+
+	if m.Method != "" && !strings.EqualFold(m.Method, e.Method) {
+		out = append(out, fmt.Sprintf("method: expected %v, got %v", strings.ToUpper(m.Method), e.Method))
+	}
+
+	if m.Status != "" {
+		s := strconv.Itoa(e.Status)
+
+		ok := s == m.Status
+		if strings.HasSuffix(strings.ToLower(m.Status), "xx") {
+			ok = s[0] == m.Status[0]
+		}
+
+		if !ok {
+			out = append(out, fmt.Sprintf("status: expected %v, got %v", m.Status, e.Status))
+		}
+	}
+
+	if !m.Since.IsZero() && e.Time.Before(m.Since) {
+		out = append(out, fmt.Sprintf("time: expected since %v, got %v", m.Since.Format(time.RFC3339Nano), e.Time.Format(time.RFC3339Nano)))
+	}
+
+	if !m.Until.IsZero() && e.Time.After(m.Until) {
+		out = append(out, fmt.Sprintf("time: expected until %v, got %v", m.Until.Format(time.RFC3339Nano), e.Time.Format(time.RFC3339Nano)))
+	}
+
+	return out
+}
+
+// parseRequestMatcher reads a matcher from query parameters, with times given
+// in RFC 3339.
+func parseRequestMatcher(q url.Values) (*RequestMatcher, error) {
+	m := &RequestMatcher{
+		Route:  q.Get("route"),
+		Path:   q.Get("path"),
+		Method: q.Get("method"),
+		Status: q.Get("status"),
+	}
+
+	var err error
+	if s := q.Get("since"); s != "" {
+		m.Since, err = time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse since: %v", err.Error())
+		}
+	}
+
+	if s := q.Get("until"); s != "" {
+		m.Until, err = time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse until: %v", err.Error())
+		}
+	}
+
+	return m, m.validate()
+}
+
+func (f *File) handleRequests(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		m, err := parseRequestMatcher(req.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"requests": f.journal.find(m)})
+	case http.MethodDelete:
+		f.journal.clear()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,196 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestJournal(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			fmt.Fprint(rw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+			rw.Flush()
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	g.Describe("Request journal", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+  /v1/teams:
+    backend: %v
+journal:
+  size: 4
+  body: 8
+`, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		find := func(query string) []*config.JournalEntry {
+			resp, err := http.Get(admin.URL + "/avenues/requests?" + query)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var body struct {
+				Requests []*config.JournalEntry `json:"requests"`
+			}
+			Expect(json.NewDecoder(resp.Body).Decode(&body)).To(BeNil())
+
+			return body.Requests
+		}
+
+		g.It("should remember requests and how they were answered", func() {
+			resp, err := http.Post(server.URL+"/v1/users/1?verbose=true", "text/plain", strings.NewReader("a long request body"))
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			resp, err = http.Get(server.URL + "/nowhere")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			entries := find("")
+			Expect(entries).To(HaveLen(2))
+
+			e := entries[0]
+			Expect(e.ID).To(Equal(int64(1)))
+			Expect(e.Method).To(Equal("POST"))
+			Expect(e.Path).To(Equal("/v1/users/1"))
+			Expect(e.Query).To(Equal("verbose=true"))
+			Expect(e.Headers.Get("Content-Type")).To(Equal("text/plain"))
+			Expect(e.Body).To(Equal("a long r"))
+			Expect(e.Truncated).To(BeTrue())
+			Expect(e.Route).To(Equal("/v1/users"))
+			Expect(e.Backend).To(Equal(backend.URL + "/v1/users/1?verbose=true"))
+			Expect(e.Status).To(Equal(http.StatusOK))
+			Expect(e.Time).NotTo(BeZero())
+
+			Expect(entries[1].Route).To(Equal(""))
+			Expect(entries[1].Status).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should remember the status of upgraded connections", func() {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/users/socket", nil)
+			Expect(err).To(BeNil())
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "echo")
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+
+			var entries []*config.JournalEntry
+			Eventually(func() []*config.JournalEntry {
+				entries = find("path=/v1/users/socket")
+				return entries
+			}).Should(HaveLen(1))
+
+			Expect(entries[0].Status).To(Equal(http.StatusSwitchingProtocols))
+			Expect(entries[0].Refused).To(BeFalse())
+		})
+
+		g.It("should filter requests", func() {
+			start := time.Now()
+
+			for _, p := range []string{"/v1/users/1", "/v1/users/2/missing", "/v1/teams/1"} {
+				resp, err := http.Get(server.URL + p)
+				Expect(err).To(BeNil())
+				resp.Body.Close()
+			}
+
+			resp, err := http.Post(server.URL+"/v1/teams/1", "text/plain", nil)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			Expect(find("route=/v1/users")).To(HaveLen(2))
+			Expect(find("path=/v1/users/{id}")).To(HaveLen(1))
+			Expect(find("path=/v1/{kind}/1")).To(HaveLen(3))
+			Expect(find("method=post")).To(HaveLen(1))
+			Expect(find("status=404")).To(HaveLen(1))
+			Expect(find("status=2xx&route=/v1/teams")).To(HaveLen(2))
+			Expect(find("since=" + url.QueryEscape(start.Add(-time.Second).Format(time.RFC3339Nano)))).To(HaveLen(4))
+			Expect(find("until=" + url.QueryEscape(start.Add(-time.Second).Format(time.RFC3339Nano)))).To(BeEmpty())
+
+			resp, err = http.Get(admin.URL + "/avenues/requests?status=abc")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		g.It("should keep only the most recent requests", func() {
+			for i := 0; i < 6; i++ {
+				resp, err := http.Get(server.URL + fmt.Sprintf("/v1/users/%v", i))
+				Expect(err).To(BeNil())
+				resp.Body.Close()
+			}
+
+			entries := find("")
+			Expect(entries).To(HaveLen(4))
+			Expect(entries[0].Path).To(Equal("/v1/users/2"))
+			Expect(entries[3].Path).To(Equal("/v1/users/5"))
+			Expect(entries[3].ID).To(Equal(int64(6)))
+		})
+
+		g.It("should leave out admin requests and clear on delete", func() {
+			resp, err := http.Get(admin.URL + "/avenues/status")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			resp, err = http.Get(server.URL + "/v1/users")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+
+			Expect(find("")).To(HaveLen(1))
+
+			req, _ := http.NewRequest(http.MethodDelete, admin.URL+"/avenues/requests", nil)
+			resp, err = http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			Expect(find("")).To(BeEmpty())
+		})
+	})
+}
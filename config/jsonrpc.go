@@ -0,0 +1,378 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/gomicro/ledger"
+)
+
+const (
+	jsonRPCVersion        = "2.0"
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInternalError  = -32603
+
+	jsonRPCTimeout = 30 * time.Second
+)
+
+// JSONRPCRule represents where to send JSON-RPC calls whose method starts with
+// the prefix
+type JSONRPCRule struct {
+	Prefix  string `yaml:"prefix"`
+	Backend string `yaml:"backend"`
+}
+
+type jsonRPCCall struct {
+	raw    json.RawMessage
+	Method string          `json:"method"`
+	ID     json.RawMessage `json:"id"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Error   *jsonRPCError   `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func loadJSONRPC(route *Route) error {
+	if len(route.Methods) == 0 && route.Backend == "" {
+		return fmt.Errorf("jsonrpc route requires methods or backend directive")
+	}
+
+	for _, rule := range route.Methods {
+		if rule.Backend == "" {
+			return fmt.Errorf("jsonrpc method '%v' requires backend directive", rule.Prefix)
+		}
+
+		err := checkBackend(rule.Backend)
+		if err != nil {
+			return fmt.Errorf("invalid jsonrpc backend: %v", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// routeJSONRPC picks the backend for the JSON-RPC calls in the request. When
+// every call goes to the same backend it is returned so the request can be
+// proxied whole. Batches spanning several backends are split, sent to each,
+// and answered with the reassembled responses, in which case true is
+// returned.
+func (f *File) routeJSONRPC(w http.ResponseWriter, req *http.Request, prefix string, route *Route, entry *JournalEntry) (string, bool) {
+	b, err := readBody(req)
+	if err != nil {
+		log.Warnf("failed to read jsonrpc request: %v", err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return "", true
+	}
+
+	calls, batch, err := parseJSONRPC(b)
+	if err != nil {
+		if route.Backend != "" {
+			log.Debugf("proxying unreadable jsonrpc request: %v", err.Error())
+			return "", false
+		}
+
+		code := jsonRPCInvalidRequest
+		if !json.Valid(b) {
+			code = jsonRPCParseError
+		}
+
+		log.Warnf("failed to read jsonrpc request: %v", err.Error())
+		setCORSHeaders(w.Header())
+		writeJSON(w, http.StatusOK, newJSONRPCError(json.RawMessage("null"), code, err.Error()))
+		return "", true
+	}
+
+	var backends []string
+	groups := map[string][]*jsonRPCCall{}
+	var unrouted []*jsonRPCCall
+
+	for _, c := range calls {
+		backend, ok := route.jsonRPCBackend(c.Method)
+		if !ok {
+			unrouted = append(unrouted, c)
+			continue
+		}
+
+		if _, ok := groups[backend]; !ok {
+			backends = append(backends, backend)
+		}
+		groups[backend] = append(groups[backend], c)
+	}
+
+	if len(unrouted) == 0 && len(backends) == 1 {
+		return backends[0], false
+	}
+
+	if !batch {
+		log.Warnf("no jsonrpc backend for method '%v'", calls[0].Method)
+
+		c := calls[0]
+		if c.notification() {
+			setCORSHeaders(w.Header())
+			w.WriteHeader(http.StatusNoContent)
+			return "", true
+		}
+
+		setCORSHeaders(w.Header())
+		writeJSON(w, http.StatusOK, newJSONRPCError(c.ID, jsonRPCMethodNotFound, "method not found"))
+		return "", true
+	}
+
+	f.splitJSONRPC(w, req, prefix, route, entry, calls, backends, groups, unrouted)
+
+	return "", true
+}
+
+func (route *Route) jsonRPCBackend(method string) (string, bool) {
+	for _, rule := range route.Methods {
+		if strings.HasPrefix(method, rule.Prefix) {
+			return rule.Backend, true
+		}
+	}
+
+	return route.Backend, route.Backend != ""
+}
+
+// splitJSONRPC sends each backend its share of the batch at once and answers
+// with the responses in the order of the calls they answer. Faults on a
+// backend apply to its share as they would to a proxied request, failing its
+// calls rather than the whole batch.
+func (f *File) splitJSONRPC(w http.ResponseWriter, req *http.Request, prefix string, route *Route, entry *JournalEntry, calls []*jsonRPCCall, backends []string, groups map[string][]*jsonRPCCall, unrouted []*jsonRPCCall) {
+	client := &http.Client{
+		Transport: f.transport,
+		Timeout:   jsonRPCTimeout,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	results := map[string]json.RawMessage{}
+	var extras []json.RawMessage
+
+	collect := func(key string, resp json.RawMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, ok := results[key]; ok || key == "" {
+			extras = append(extras, resp)
+			return
+		}
+
+		results[key] = resp
+	}
+
+	for _, c := range unrouted {
+		if !c.notification() {
+			collect(c.key(), newJSONRPCError(c.ID, jsonRPCMethodNotFound, "method not found"))
+		}
+	}
+
+	targets := make([]string, len(backends))
+	for i, backend := range backends {
+		targets[i] = backend
+		if u, err := targetURL(backend, req.URL); err == nil {
+			targets[i] = u.String()
+		}
+	}
+	entry.Backend = strings.Join(targets, ", ")
+
+	for _, backend := range backends {
+		wg.Add(1)
+
+		go func(backend string, group []*jsonRPCCall) {
+			defer wg.Done()
+
+			u, err := targetURL(backend, req.URL)
+			if err == nil {
+				if fault, ok := f.faults.find(prefix, u); ok {
+					err = fault.interrupt()
+				}
+			}
+
+			var resps []json.RawMessage
+			if err == nil {
+				resps, err = forwardJSONRPC(client, req, u, group)
+			}
+
+			if err != nil {
+				log.Errorf("failed to forward jsonrpc batch to '%v': %v", backend, err.Error())
+				route.fail(err)
+
+				for _, c := range group {
+					if !c.notification() {
+						collect(c.key(), newJSONRPCError(c.ID, jsonRPCInternalError, err.Error()))
+					}
+				}
+
+				return
+			}
+
+			for _, r := range resps {
+				var resp jsonRPCResponse
+				_ = json.Unmarshal(r, &resp)
+				collect(compactJSON(resp.ID), r)
+			}
+		}(backend, groups[backend])
+	}
+
+	wg.Wait()
+
+	out := []json.RawMessage{}
+	for _, c := range calls {
+		if r, ok := results[c.key()]; ok && !c.notification() {
+			out = append(out, r)
+			delete(results, c.key())
+		}
+	}
+	out = append(out, extras...)
+
+	setCORSHeaders(w.Header())
+
+	if len(out) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+	log.Infof("split jsonrpc batch for '%v' across %v backends", req.URL, len(backends))
+}
+
+func forwardJSONRPC(client *http.Client, orig *http.Request, u *url.URL, group []*jsonRPCCall) ([]json.RawMessage, error) {
+	batch := make([]json.RawMessage, len(group))
+	for i, c := range group {
+		batch[i] = c.raw
+	}
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %v", err.Error())
+	}
+
+	req, err := http.NewRequest(orig.Method, u.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err.Error())
+	}
+	req.Header = orig.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Add("X-Forwarded-Host", orig.Host)
+	req.Header.Add("X-Origin-Host", u.Host)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err.Error())
+	}
+
+	rb = bytes.TrimSpace(rb)
+	if len(rb) == 0 {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("unexpected status: %v", resp.Status)
+		}
+
+		return nil, nil
+	}
+
+	if rb[0] != '[' {
+		if !json.Valid(rb) {
+			return nil, fmt.Errorf("unexpected response: %v", resp.Status)
+		}
+
+		return []json.RawMessage{rb}, nil
+	}
+
+	var resps []json.RawMessage
+	err = json.Unmarshal(rb, &resps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %v", err.Error())
+	}
+
+	return resps, nil
+}
+
+// parseJSONRPC reads the calls in a request body, reporting whether they were
+// sent as a batch.
+func parseJSONRPC(b []byte) ([]*jsonRPCCall, bool, error) {
+	b = bytes.TrimSpace(b)
+
+	var raws []json.RawMessage
+	batch := len(b) > 0 && b[0] == '['
+
+	if batch {
+		err := json.Unmarshal(b, &raws)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to unmarshal batch: %v", err.Error())
+		}
+
+		if len(raws) == 0 {
+			return nil, true, fmt.Errorf("batch is empty")
+		}
+	} else {
+		raws = []json.RawMessage{b}
+	}
+
+	calls := make([]*jsonRPCCall, len(raws))
+	for i, raw := range raws {
+		c := &jsonRPCCall{raw: raw}
+
+		err := json.Unmarshal(raw, c)
+		if err != nil {
+			return nil, batch, fmt.Errorf("failed to unmarshal call: %v", err.Error())
+		}
+
+		if c.Method == "" {
+			return nil, batch, fmt.Errorf("call is missing method")
+		}
+
+		calls[i] = c
+	}
+
+	return calls, batch, nil
+}
+
+func (c *jsonRPCCall) notification() bool {
+	return len(c.ID) == 0
+}
+
+func (c *jsonRPCCall) key() string {
+	return compactJSON(c.ID)
+}
+
+func compactJSON(raw json.RawMessage) string {
+	var b bytes.Buffer
+	if json.Compact(&b, raw) != nil {
+		return ""
+	}
+
+	return b.String()
+}
+
+func newJSONRPCError(id json.RawMessage, code int, message string) json.RawMessage {
+	b, _ := json.Marshal(jsonRPCResponse{
+		JSONRPC: jsonRPCVersion,
+		Error:   &jsonRPCError{Code: code, Message: message},
+		ID:      id,
+	})
+
+	return b
+}
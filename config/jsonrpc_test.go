@@ -0,0 +1,218 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestJSONRPC(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	// newBackend answers each call with its method and the backend's name,
+	// answering batches in reverse to check responses are reordered
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+
+			type call struct {
+				Method string          `json:"method"`
+				ID     json.RawMessage `json:"id,omitempty"`
+			}
+
+			answer := func(c call) map[string]interface{} {
+				return map[string]interface{}{
+					"jsonrpc": "2.0",
+					"result":  name + ":" + c.Method,
+					"id":      c.ID,
+				}
+			}
+
+			var batch []call
+			if json.Unmarshal(b, &batch) != nil {
+				var c call
+				json.Unmarshal(b, &c)
+				json.NewEncoder(w).Encode(answer(c))
+				return
+			}
+
+			out := []interface{}{}
+			for i := len(batch) - 1; i >= 0; i-- {
+				if len(batch[i].ID) > 0 {
+					out = append(out, answer(batch[i]))
+				}
+			}
+
+			if len(out) == 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			json.NewEncoder(w).Encode(out)
+		}))
+	}
+
+	chain := newBackend("chain")
+	defer chain.Close()
+
+	wallet := newBackend("wallet")
+	defer wallet.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	g.Describe("JSON-RPC routes", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /rpc:
+    type: jsonrpc
+    backend: %v
+    methods:
+      - prefix: wallet_
+        backend: %v
+      - prefix: broken_
+        backend: %v
+  /strict/:
+    type: jsonrpc
+    methods:
+      - prefix: wallet_
+        backend: %v
+`, chain.URL, wallet.URL, down.URL, wallet.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		post := func(path, body string) (int, []map[string]interface{}) {
+			resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).To(BeNil())
+
+			var out []map[string]interface{}
+			if len(b) > 0 && b[0] == '[' {
+				Expect(json.Unmarshal(b, &out)).To(BeNil())
+			} else if len(b) > 0 {
+				var single map[string]interface{}
+				Expect(json.Unmarshal(b, &single)).To(BeNil())
+				out = append(out, single)
+			}
+
+			return resp.StatusCode, out
+		}
+
+		g.It("should route single calls by method prefix", func() {
+			status, out := post("/rpc", `{"jsonrpc":"2.0","method":"wallet_balance","id":1}`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(out[0]["result"]).To(Equal("wallet:wallet_balance"))
+
+			_, out = post("/rpc", `{"jsonrpc":"2.0","method":"eth_blockNumber","id":2}`)
+			Expect(out[0]["result"]).To(Equal("chain:eth_blockNumber"))
+		})
+
+		g.It("should proxy batches for a single backend whole", func() {
+			_, out := post("/rpc", `[{"jsonrpc":"2.0","method":"wallet_a","id":1},{"jsonrpc":"2.0","method":"wallet_b","id":2}]`)
+			Expect(out).To(HaveLen(2))
+			Expect(out[0]["result"]).To(Equal("wallet:wallet_b"))
+		})
+
+		g.It("should split and reassemble batches across backends", func() {
+			status, out := post("/rpc", `[
+  {"jsonrpc":"2.0","method":"eth_a","id":1},
+  {"jsonrpc":"2.0","method":"wallet_b","id":"two"},
+  {"jsonrpc":"2.0","method":"wallet_notify"},
+  {"jsonrpc":"2.0","method":"eth_c","id":3},
+  {"jsonrpc":"2.0","method":"broken_d","id":4}
+]`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(out).To(HaveLen(4))
+
+			Expect(out[0]["id"]).To(Equal(float64(1)))
+			Expect(out[0]["result"]).To(Equal("chain:eth_a"))
+			Expect(out[1]["id"]).To(Equal("two"))
+			Expect(out[1]["result"]).To(Equal("wallet:wallet_b"))
+			Expect(out[2]["id"]).To(Equal(float64(3)))
+			Expect(out[2]["result"]).To(Equal("chain:eth_c"))
+			Expect(out[3]["id"]).To(Equal(float64(4)))
+			Expect(out[3]["error"].(map[string]interface{})["code"]).To(Equal(float64(-32603)))
+		})
+
+		g.It("should apply backend faults to split batches", func() {
+			resp, err := http.Post(admin.URL+"/avenues/faults", "application/json", strings.NewReader(fmt.Sprintf(`{"backend": %q, "down": true}`, wallet.URL)))
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			_, out := post("/rpc", `[{"jsonrpc":"2.0","method":"wallet_a","id":1},{"jsonrpc":"2.0","method":"eth_b","id":2}]`)
+			Expect(out).To(HaveLen(2))
+			Expect(out[0]["error"]).To(HaveKeyWithValue("code", float64(-32603)))
+			Expect(out[1]["result"]).To(Equal("chain:eth_b"))
+
+			resp, err = http.Get(admin.URL + "/avenues/requests?route=/rpc")
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			var journal struct {
+				Requests []*config.JournalEntry `json:"requests"`
+			}
+			Expect(json.NewDecoder(resp.Body).Decode(&journal)).To(BeNil())
+			Expect(journal.Requests).To(HaveLen(1))
+			Expect(journal.Requests[0].Backend).To(Equal(fmt.Sprintf("%v/rpc, %v/rpc", wallet.URL, chain.URL)))
+		})
+
+		g.It("should answer calls with no backend with errors", func() {
+			status, out := post("/strict/", `{"jsonrpc":"2.0","method":"eth_a","id":1}`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(out[0]["error"].(map[string]interface{})["code"]).To(Equal(float64(-32601)))
+
+			_, out = post("/strict/", `[{"jsonrpc":"2.0","method":"eth_a","id":1},{"jsonrpc":"2.0","method":"wallet_b","id":2}]`)
+			Expect(out).To(HaveLen(2))
+			Expect(out[0]["error"].(map[string]interface{})["code"]).To(Equal(float64(-32601)))
+			Expect(out[1]["result"]).To(Equal("wallet:wallet_b"))
+
+			status, out = post("/strict/", `{"jsonrpc":"2.0","method":"eth_a"}`)
+			Expect(status).To(Equal(http.StatusNoContent))
+			Expect(out).To(BeEmpty())
+
+			_, out = post("/strict/", `{"jsonrpc":`)
+			Expect(out[0]["error"].(map[string]interface{})["code"]).To(Equal(float64(-32700)))
+
+			_, out = post("/strict/", `[]`)
+			Expect(out[0]["error"].(map[string]interface{})["code"]).To(Equal(float64(-32600)))
+		})
+
+		g.It("should reject rules without a backend", func() {
+			_, err := config.Parse([]byte(`
+routes:
+  /rpc:
+    type: jsonrpc
+    methods:
+      - prefix: eth_
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
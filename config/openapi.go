@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gomicro/avenues/openapi"
+	log "github.com/gomicro/ledger"
+)
+
+func loadOpenAPI(route *Route) error {
+	if route.Spec == "" {
+		return fmt.Errorf("openapi route requires spec directive")
+	}
+
+	doc, err := openapi.Load(route.Spec)
+	if err != nil {
+		return err
+	}
+
+	route.spec = doc
+
+	return nil
+}
+
+// mockOpenAPI answers the request with a response synthesised from the
+// route's spec. Requests for operations missing from the spec are answered
+// with a 404, unless the route has a backend, in which case false is returned
+// and the request should be proxied instead.
+func mockOpenAPI(w http.ResponseWriter, req *http.Request, route *Route) bool {
+	m, ok := route.spec.Find(req.Method, req.URL.Path)
+	if !ok {
+		if route.Backend != "" {
+			return false
+		}
+
+		log.Warnf("no operation in spec for '%v %v'", req.Method, req.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return true
+	}
+
+	mock, err := route.spec.Mock(m.Operation, openapi.ParsePrefer(req.Header.Get("Prefer")))
+	if err != nil {
+		log.Warnf("failed to mock '%v %v': %v", req.Method, req.URL.Path, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+
+	setCORSHeaders(w.Header())
+	if mock.ContentType != "" {
+		w.Header().Set("Content-Type", mock.ContentType)
+	}
+
+	w.WriteHeader(mock.Status)
+	_, err = w.Write(mock.Body)
+	if err != nil {
+		log.Errorf("internal error writing body: %v", err.Error())
+	}
+
+	log.Infof("mocked '%v %v' from '%v'", req.Method, req.URL, m.Path)
+
+	return true
+}
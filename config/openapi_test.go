@@ -0,0 +1,118 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestOpenAPI(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	g.Describe("OpenAPI Mocks", func() {
+		var server *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/pets:
+    type: openapi
+    spec: ./petstore.yaml
+  /v1/owners:
+    type: openapi
+    spec: ./petstore.yaml
+    backend: %v
+`, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+		})
+
+		get := func(path, prefer string) (*http.Response, []byte) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+			Expect(err).To(BeNil())
+
+			if prefer != "" {
+				req.Header.Set("Prefer", prefer)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			b, err := ioutil.ReadAll(res.Body)
+			Expect(err).To(BeNil())
+
+			return res, b
+		}
+
+		g.It("should synthesise a response from the schema", func() {
+			res, b := get("/v1/pets", "")
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			var pets []map[string]interface{}
+			Expect(json.Unmarshal(b, &pets)).To(BeNil())
+			Expect(len(pets)).To(Equal(1))
+			Expect(pets[0]["name"]).To(Equal("Fido"))
+		})
+
+		g.It("should answer with the first example", func() {
+			res, b := get("/v1/pets/1", "")
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(b).To(MatchJSON(`{"id": 1, "name": "Rex"}`))
+		})
+
+		g.It("should honour a preferred example", func() {
+			res, b := get("/v1/pets/2", "example=whiskers")
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(b).To(MatchJSON(`{"id": 2, "name": "Whiskers", "tag": "cat"}`))
+		})
+
+		g.It("should honour a preferred code", func() {
+			res, b := get("/v1/pets/2", "code=404")
+
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(b).To(MatchJSON(`{"message": "pet not found"}`))
+		})
+
+		g.It("should reject a preferred code the operation does not define", func() {
+			res, _ := get("/v1/pets/2", "code=418")
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		g.It("should answer not found for operations missing from the spec", func() {
+			res, _ := get("/v1/pets/2/toys", "")
+
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should proxy operations missing from the spec when a backend is set", func() {
+			res, _ := get("/v1/owners", "")
+
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+		})
+	})
+}
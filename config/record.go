@@ -0,0 +1,307 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCassetteDir = "./cassettes"
+
+	perRouteCassette   = "route"
+	perSessionCassette = "session"
+
+	redacted = "[REDACTED]"
+)
+
+// Record represents the options for writing proxied traffic to cassettes.
+// The traffic of routes that record is also kept for exporting as a HAR,
+// along with that of routes that only capture it without writing cassettes.
+type Record struct {
+	Dir    string  `yaml:"dir"`
+	Per    string  `yaml:"per"`
+	Redact *Redact `yaml:"redact,omitempty"`
+}
+
+// Redact represents the rules for scrubbing sensitive values from recorded
+// interactions. Headers are matched by name and body rules are regular
+// expressions whose matches are replaced.
+type Redact struct {
+	Headers []string `yaml:"headers,omitempty"`
+	Body    []string `yaml:"body,omitempty"`
+}
+
+type recorder struct {
+	sync.Mutex
+	conf      *Record
+	headers   []string
+	body      []*regexp.Regexp
+	session   string
+	cassettes map[string]*Cassette
+	written   map[string]bool
+	captured  []*Interaction
+	start     int
+	count     int
+}
+
+// newRecorder prepares a recorder keeping the most recent interactions, up to
+// the given number, for exporting
+func newRecorder(conf *Record, keep int) (*recorder, error) {
+	if conf.Dir == "" {
+		conf.Dir = defaultCassetteDir
+	}
+
+	switch strings.ToLower(conf.Per) {
+	case "":
+		conf.Per = perRouteCassette
+	case perRouteCassette, perSessionCassette:
+	default:
+		return nil, fmt.Errorf("unknown cassette grouping: %v", conf.Per)
+	}
+
+	r := &recorder{
+		conf:      conf,
+		session:   fmt.Sprintf("session-%v.yaml", time.Now().UTC().Format("20060102T150405")),
+		cassettes: make(map[string]*Cassette),
+		written:   make(map[string]bool),
+		captured:  make([]*Interaction, keep),
+	}
+
+	if conf.Redact != nil {
+		r.headers = conf.Redact.Headers
+
+		for _, expr := range conf.Redact.Body {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile body redaction: %v", err.Error())
+			}
+
+			r.body = append(r.body, re)
+		}
+	}
+
+	return r, nil
+}
+
+// newInteraction captures the request half of an interaction, restoring the
+// request body so it can still be proxied.
+func newInteraction(prefix string, req *http.Request) (*Interaction, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Interaction{
+		Route: prefix,
+		Request: &RecordedRequest{
+			Method:  req.Method,
+			Host:    req.Host,
+			Path:    req.URL.Path,
+			Query:   req.URL.RawQuery,
+			Headers: req.Header.Clone(),
+			Body:    string(body),
+		},
+	}, nil
+}
+
+// captureResponse fills in the response half of an interaction, restoring
+// the response body so it can still be served.
+func (in *Interaction) captureResponse(resp *http.Response) error {
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err.Error())
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	in.RecordedAt = time.Now().UTC()
+	in.Response = &RecordedResponse{
+		Status:  resp.StatusCode,
+		Headers: resp.Header.Clone(),
+		Body:    string(b),
+	}
+
+	return nil
+}
+
+func (r *recorder) record(in *Interaction) error {
+	name := r.session
+	if r.conf.Per == perRouteCassette {
+		name = cassetteName(in.Route)
+	}
+
+	return r.save(filepath.Join(r.conf.Dir, name), in)
+}
+
+// save redacts the interaction and appends it to the cassette at the given
+// path. The whole cassette is only written the first time it is saved to, so
+// it is in a form that later interactions can be appended to. Only cassettes
+// replay routes answer from are kept in memory.
+func (r *recorder) save(path string, in *Interaction) error {
+	r.redact(in)
+
+	b, err := marshalInteractions(in)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	c, held := r.cassettes[path]
+	if held {
+		c.Interactions = append(c.Interactions, in)
+	}
+	r.capture(in)
+
+	if r.written[path] {
+		return appendInteractions(path, b)
+	}
+
+	if !held {
+		c, err = readCassette(path)
+		if err != nil {
+			return err
+		}
+
+		c.Interactions = append(c.Interactions, in)
+	}
+
+	err = c.Save(path)
+	if err != nil {
+		return err
+	}
+
+	r.written[path] = true
+
+	return nil
+}
+
+// export redacts the interaction and keeps it for exporting without writing
+// it to a cassette
+func (r *recorder) export(in *Interaction) {
+	r.redact(in)
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.capture(in)
+}
+
+// capture keeps the interaction for exporting, dropping the oldest when full.
+// The caller must hold the recorder's lock.
+func (r *recorder) capture(in *Interaction) {
+	if len(r.captured) == 0 {
+		return
+	}
+
+	r.captured[(r.start+r.count)%len(r.captured)] = in
+
+	if r.count < len(r.captured) {
+		r.count++
+	} else {
+		r.start = (r.start + 1) % len(r.captured)
+	}
+}
+
+// capturedInteractions returns the most recent interactions recorded since
+// Avenues started or was last reset, oldest first.
+func (r *recorder) capturedInteractions() []*Interaction {
+	r.Lock()
+	defer r.Unlock()
+
+	captured := make([]*Interaction, r.count)
+	for n := 0; n < r.count; n++ {
+		captured[n] = r.captured[(r.start+n)%len(r.captured)]
+	}
+
+	return captured
+}
+
+func (r *recorder) clearCaptured() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.captured = make([]*Interaction, len(r.captured))
+	r.start = 0
+	r.count = 0
+}
+
+// cassette returns the cassette for the given path, loading it from disk the
+// first time it is requested. A missing file results in an empty cassette.
+// The caller must hold the recorder's lock.
+func (r *recorder) cassette(path string) (*Cassette, error) {
+	c, ok := r.cassettes[path]
+	if ok {
+		return c, nil
+	}
+
+	c, err := readCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cassettes[path] = c
+
+	return c, nil
+}
+
+// readCassette loads the cassette at the given path, or an empty cassette
+// when the file is missing.
+func readCassette(path string) (*Cassette, error) {
+	if _, err := os.Stat(path); err != nil {
+		return &Cassette{}, nil
+	}
+
+	return LoadCassette(path)
+}
+
+func (r *recorder) redact(in *Interaction) {
+	for _, h := range r.headers {
+		redactHeader(in.Request.Headers, h)
+		redactHeader(in.Response.Headers, h)
+	}
+
+	for _, re := range r.body {
+		in.Request.Body = re.ReplaceAllString(in.Request.Body, redacted)
+		in.Response.Body = re.ReplaceAllString(in.Response.Body, redacted)
+	}
+}
+
+func redactHeader(h http.Header, name string) {
+	if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+		h.Set(name, redacted)
+	}
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %v", err.Error())
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	return b, nil
+}
+
+func cassetteName(prefix string) string {
+	name := strings.Trim(prefix, "/")
+	if name == "" {
+		name = "root"
+	}
+
+	return fmt.Sprintf("%v.yaml", strings.ReplaceAll(name, "/", "_"))
+}
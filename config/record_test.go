@@ -0,0 +1,184 @@
+package config_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestRecord(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 1, "password": "hunter2"}`))
+	}))
+	defer backend.Close()
+
+	g.Describe("Recording", func() {
+		var dir string
+
+		g.BeforeEach(func() {
+			d, err := ioutil.TempDir("", "cassettes")
+			Expect(err).To(BeNil())
+			dir = d
+		})
+
+		g.AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		g.It("should record interactions per route with redaction", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+record:
+  dir: %v
+  redact:
+    headers:
+      - Authorization
+      - Set-Cookie
+    body:
+      - '"password": "[^"]*"'
+routes:
+  /v1/foo:
+    backend: %v
+    record: true
+  /v1/bar:
+    backend: %v
+`, dir, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/foo/users?a=b", bytes.NewBufferString(`{"password": "hunter2"}`))
+			Expect(err).To(BeNil())
+			req.Header.Set("Authorization", "Bearer token")
+
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(string(b)).To(ContainSubstring("hunter2"))
+
+			res, err = http.Get(server.URL + "/v1/bar")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			cas, err := config.LoadCassette(filepath.Join(dir, "v1_foo.yaml"))
+			Expect(err).To(BeNil())
+			Expect(len(cas.Interactions)).To(Equal(1))
+
+			in := cas.Interactions[0]
+			Expect(in.Route).To(Equal("/v1/foo"))
+			Expect(in.Request.Method).To(Equal(http.MethodPost))
+			Expect(in.Request.Path).To(Equal("/v1/foo/users"))
+			Expect(in.Request.Query).To(Equal("a=b"))
+			Expect(in.Request.Headers.Get("Authorization")).To(Equal("[REDACTED]"))
+			Expect(in.Request.Body).To(Equal("{[REDACTED]}"))
+			Expect(in.Response.Status).To(Equal(http.StatusCreated))
+			Expect(in.Response.Headers.Get("Set-Cookie")).To(Equal("[REDACTED]"))
+			Expect(in.Response.Body).To(Equal(`{"id": 1, [REDACTED]}`))
+
+			_, err = os.Stat(filepath.Join(dir, "v1_bar.yaml"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		g.It("should record interactions per session", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+record:
+  dir: %v
+  per: session
+routes:
+  /v1/foo:
+    backend: %v
+    record: true
+  /v1/bar:
+    backend: %v
+    record: true
+`, dir, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			for _, p := range []string{"/v1/foo", "/v1/bar"} {
+				res, err := http.Get(server.URL + p)
+				Expect(err).To(BeNil())
+				res.Body.Close()
+			}
+
+			files, err := filepath.Glob(filepath.Join(dir, "session-*.yaml"))
+			Expect(err).To(BeNil())
+			Expect(len(files)).To(Equal(1))
+
+			cas, err := config.LoadCassette(files[0])
+			Expect(err).To(BeNil())
+			Expect(len(cas.Interactions)).To(Equal(2))
+		})
+
+		g.It("should append to existing cassettes", func() {
+			existing := &config.Cassette{Interactions: []*config.Interaction{{
+				Route:    "/v1/foo",
+				Request:  &config.RecordedRequest{Method: http.MethodGet, Path: "/v1/foo/old"},
+				Response: &config.RecordedResponse{Status: http.StatusOK},
+			}}}
+			Expect(existing.Save(filepath.Join(dir, "v1_foo.yaml"))).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "v1_bar.yaml"), []byte("interactions: []\n"), 0644)).To(BeNil())
+
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+record:
+  dir: %v
+routes:
+  /v1/foo:
+    backend: %v
+    record: true
+  /v1/bar:
+    backend: %v
+    record: true
+`, dir, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			for _, p := range []string{"/v1/foo/1", "/v1/foo/2", "/v1/bar/1", "/v1/bar/2"} {
+				res, err := http.Get(server.URL + p)
+				Expect(err).To(BeNil())
+				res.Body.Close()
+			}
+
+			cas, err := config.LoadCassette(filepath.Join(dir, "v1_foo.yaml"))
+			Expect(err).To(BeNil())
+			Expect(len(cas.Interactions)).To(Equal(3))
+			Expect(cas.Interactions[0].Request.Path).To(Equal("/v1/foo/old"))
+			Expect(cas.Interactions[2].Request.Path).To(Equal("/v1/foo/2"))
+
+			cas, err = config.LoadCassette(filepath.Join(dir, "v1_bar.yaml"))
+			Expect(err).To(BeNil())
+			Expect(len(cas.Interactions)).To(Equal(2))
+			Expect(cas.Interactions[1].Request.Path).To(Equal("/v1/bar/2"))
+		})
+
+		g.It("should reject an unknown cassette grouping", func() {
+			_, err := config.Parse([]byte(`
+record:
+  per: weekly
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
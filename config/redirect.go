@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/gomicro/ledger"
+)
+
+func loadRedirect(route *Route) error {
+	if route.Target == "" {
+		return fmt.Errorf("redirect route requires target directive")
+	}
+
+	switch route.Status {
+	case 0:
+		route.Status = http.StatusFound
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("unsupported redirect status: %v", route.Status)
+	}
+
+	return nil
+}
+
+// redirect answers the request with a redirect to the route's target. Requests
+// not matching the route's from pattern are answered with a 404, unless the
+// route has a backend, in which case false is returned and the request should
+// be proxied instead.
+func redirect(w http.ResponseWriter, req *http.Request, prefix string, route *Route) bool {
+	params := map[string]string{
+		"path": req.URL.EscapedPath(),
+		"rest": strings.TrimPrefix(req.URL.EscapedPath(), strings.TrimSuffix(prefix, "/")),
+	}
+
+	if route.From != "" {
+		named, ok := matchPattern(route.From, req.URL.Path)
+		if !ok {
+			if route.Backend != "" {
+				return false
+			}
+
+			log.Warnf("no redirect for url: %v", req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return true
+		}
+
+		for k, v := range named {
+			params[k] = url.PathEscape(v)
+		}
+	}
+
+	target := expandTemplate(route.Target, params)
+
+	if route.PreserveQuery && req.URL.RawQuery != "" {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+
+		target = fmt.Sprintf("%v%v%v", target, sep, req.URL.RawQuery)
+	}
+
+	w.Header().Set("Location", target)
+	w.WriteHeader(route.Status)
+	log.Infof("redirected '%v' to '%v'", req.URL, target)
+
+	return true
+}
+
+// matchPattern matches a path against a pattern where segments in braces,
+// such as /users/{id}, capture the corresponding path segment.
+func matchPattern(pattern, path string) (map[string]string, bool) {
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	ss := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(ps) != len(ss) || strings.HasSuffix(path, "/") != strings.HasSuffix(pattern, "/") {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, p := range ps {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			params[strings.Trim(p, "{}")] = ss[i]
+			continue
+		}
+
+		if p != ss[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func expandTemplate(tmpl string, params map[string]string) string {
+	for k, v := range params {
+		tmpl = strings.ReplaceAll(tmpl, fmt.Sprintf("{%v}", k), v)
+	}
+
+	return tmpl
+}
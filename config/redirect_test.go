@@ -0,0 +1,113 @@
+package config_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestRedirect(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	g.Describe("Redirects", func() {
+		var server *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v0/:
+    type: redirect
+    target: /v1{rest}
+  /old/users/:
+    type: redirect
+    status: 308
+    from: /old/users/{id}/posts/{post}
+    target: https://api.example.com/v2/posts/{post}?author={id}
+    preserve_query: true
+  /v1/teams:
+    type: redirect
+    status: 301
+    from: /v1/teams
+    target: /v1/teams/
+    backend: %v
+`, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+		})
+
+		g.It("should redirect using the rest of the path", func() {
+			res, err := client.Get(server.URL + "/v0/users/1?a=b")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusFound))
+			Expect(res.Header.Get("Location")).To(Equal("/v1/users/1"))
+		})
+
+		g.It("should redirect using path parameters and preserve the query", func() {
+			res, err := client.Get(server.URL + "/old/users/7/posts/42?page=2")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusPermanentRedirect))
+			Expect(res.Header.Get("Location")).To(Equal("https://api.example.com/v2/posts/42?author=7&page=2"))
+		})
+
+		g.It("should not redirect paths that do not match the pattern", func() {
+			res, err := client.Get(server.URL + "/old/users/7")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should normalise trailing slashes and proxy the rest", func() {
+			res, err := client.Get(server.URL + "/v1/teams")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusMovedPermanently))
+			Expect(res.Header.Get("Location")).To(Equal("/v1/teams/"))
+
+			res, err = client.Get(server.URL + "/v1/teams/")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+		})
+
+		g.It("should reject unsupported statuses", func() {
+			_, err := config.Parse([]byte(`
+routes:
+  /v0:
+    type: redirect
+    status: 200
+    target: /v1
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
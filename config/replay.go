@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	log "github.com/gomicro/ledger"
+)
+
+const (
+	notFoundUnmatched = "not_found"
+	backendUnmatched  = "backend"
+	recordUnmatched   = "record"
+)
+
+func (f *File) loadReplay(route *Route) error {
+	if route.Cassette == "" && route.HAR == "" {
+		return fmt.Errorf("replay route requires cassette or har directive")
+	}
+
+	if route.Cassette != "" && route.HAR != "" {
+		return fmt.Errorf("replay route accepts only one of cassette or har directives")
+	}
+
+	switch route.Unmatched {
+	case "":
+		route.Unmatched = notFoundUnmatched
+	case notFoundUnmatched:
+	case backendUnmatched, recordUnmatched:
+		if route.Unmatched == recordUnmatched && route.HAR != "" {
+			return fmt.Errorf("replay route requires cassette directive for unmatched policy: %v", route.Unmatched)
+		}
+
+		if route.Backend == "" {
+			return fmt.Errorf("replay route requires backend directive for unmatched policy: %v", route.Unmatched)
+		}
+	default:
+		return fmt.Errorf("unknown unmatched policy: %v", route.Unmatched)
+	}
+
+	if route.Cassette != "" && route.Unmatched != recordUnmatched {
+		_, err := os.Stat(route.Cassette)
+		if err != nil {
+			return fmt.Errorf("failed to find cassette: %v", err.Error())
+		}
+	}
+
+	f.recorder.Lock()
+	defer f.recorder.Unlock()
+
+	if route.HAR != "" {
+		c, err := LoadHAR(route.HAR)
+		if err != nil {
+			return err
+		}
+
+		f.recorder.cassettes[route.HAR] = c
+		return nil
+	}
+
+	_, err := f.recorder.cassette(route.Cassette)
+
+	return err
+}
+
+// replay answers the request from the route's cassette, returning true when
+// the request has been handled and must not be proxied.
+func (f *File) replay(w http.ResponseWriter, req *http.Request, route *Route) bool {
+	body, err := readBody(req)
+	if err != nil {
+		log.Warnf("failed to replay request: %v", err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return true
+	}
+
+	in, err := f.recorder.match(route.source(), req, body, route.MatchBody)
+	if err != nil {
+		log.Errorf("failed to replay request: %v", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	if in == nil {
+		if route.Unmatched != notFoundUnmatched {
+			return false
+		}
+
+		log.Warnf("no recorded interaction for '%v %v'", req.Method, req.URL)
+		w.WriteHeader(http.StatusNotFound)
+		return true
+	}
+
+	writeRecordedResponse(w, in.Response)
+	log.Infof("replayed '%v %v' from '%v'", req.Method, req.URL, route.source())
+
+	return true
+}
+
+// source is the file a replay route answers from
+func (r *Route) source() string {
+	if r.HAR != "" {
+		return r.HAR
+	}
+
+	return r.Cassette
+}
+
+func (r *recorder) match(path string, req *http.Request, body []byte, matchBody bool) (*Interaction, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	c, err := r.cassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	query := normalizeQuery(req.URL.RawQuery)
+
+	for _, in := range c.Interactions {
+		if in.Request == nil || in.Response == nil {
+			continue
+		}
+
+		if in.Request.Method != req.Method || in.Request.Path != req.URL.Path {
+			continue
+		}
+
+		if normalizeQuery(in.Request.Query) != query {
+			continue
+		}
+
+		if matchBody && !bytes.Equal(bytes.TrimSpace([]byte(in.Request.Body)), bytes.TrimSpace(body)) {
+			continue
+		}
+
+		return in, nil
+	}
+
+	return nil, nil
+}
+
+func writeRecordedResponse(w http.ResponseWriter, resp *RecordedResponse) {
+	for k, vs := range resp.Headers {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+
+	w.Header().Del("Content-Length")
+	setCORSHeaders(w.Header())
+
+	w.WriteHeader(resp.Status)
+	_, err := w.Write([]byte(resp.Body))
+	if err != nil {
+		log.Errorf("internal error writing body: %v", err.Error())
+	}
+}
+
+func normalizeQuery(raw string) string {
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+
+	return q.Encode()
+}
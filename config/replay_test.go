@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+const replayCassette = `
+interactions:
+  - route: /v1/foo
+    request:
+      method: GET
+      path: /v1/foo/users
+      query: b=2&a=1
+    response:
+      status: 200
+      headers:
+        Content-Type:
+          - application/json
+      body: '[{"id": 1}]'
+  - route: /v1/foo
+    request:
+      method: POST
+      path: /v1/foo/users
+      body: '{"name": "alice"}'
+    response:
+      status: 201
+      body: '{"id": 2}'
+`
+
+func TestReplay(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("from backend"))
+	}))
+	defer backend.Close()
+
+	g.Describe("Replaying", func() {
+		var dir string
+		var cassette string
+
+		g.BeforeEach(func() {
+			d, err := ioutil.TempDir("", "cassettes")
+			Expect(err).To(BeNil())
+			dir = d
+
+			cassette = filepath.Join(dir, "foo.yaml")
+			err = ioutil.WriteFile(cassette, []byte(replayCassette), 0644)
+			Expect(err).To(BeNil())
+		})
+
+		g.AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		serve := func(extra string) *httptest.Server {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/foo:
+    type: replay
+    cassette: %v
+    backend: %v
+%v
+`, cassette, backend.URL, extra)))
+			Expect(err).To(BeNil())
+
+			return httptest.NewServer(c)
+		}
+
+		g.It("should answer from a recorded interaction", func() {
+			server := serve("")
+			defer server.Close()
+
+			res, err := http.Get(server.URL + "/v1/foo/users?a=1&b=2")
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(string(b)).To(Equal(`[{"id": 1}]`))
+		})
+
+		g.It("should answer not found for unmatched requests by default", func() {
+			server := serve("")
+			defer server.Close()
+
+			res, err := http.Get(server.URL + "/v1/foo/users?a=3")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should match on the body when configured", func() {
+			server := serve("    match_body: true")
+			defer server.Close()
+
+			res, err := http.Post(server.URL+"/v1/foo/users", "application/json", bytes.NewBufferString(`{"name": "alice"}`))
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+
+			res, err = http.Post(server.URL+"/v1/foo/users", "application/json", bytes.NewBufferString(`{"name": "bob"}`))
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should fall through to the backend", func() {
+			server := serve("    unmatched: backend")
+			defer server.Close()
+
+			res, err := http.Get(server.URL + "/v1/foo/other")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+		})
+
+		g.It("should record new interactions", func() {
+			server := serve("    unmatched: record")
+			defer server.Close()
+
+			for i := 0; i < 2; i++ {
+				res, err := http.Get(server.URL + "/v1/foo/other")
+				Expect(err).To(BeNil())
+				b, _ := ioutil.ReadAll(res.Body)
+				res.Body.Close()
+
+				Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+				Expect(string(b)).To(Equal("from backend"))
+			}
+
+			cas, err := config.LoadCassette(cassette)
+			Expect(err).To(BeNil())
+			Expect(len(cas.Interactions)).To(Equal(3))
+			Expect(cas.Interactions[2].Request.Path).To(Equal("/v1/foo/other"))
+		})
+
+		g.It("should require a cassette", func() {
+			_, err := config.Parse([]byte(`
+routes:
+  /v1/foo:
+    type: replay
+`))
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("cassette"))
+		})
+	})
+}
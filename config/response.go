@@ -0,0 +1,33 @@
+package config
+
+import (
+	"net/http"
+
+	log "github.com/gomicro/ledger"
+)
+
+// Response represents a canned response Avenues answers with in place of a
+// backend
+type Response struct {
+	Status  int               `yaml:"status,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+func (r *Response) write(w http.ResponseWriter) {
+	setCORSHeaders(w.Header())
+	for k, v := range r.Headers {
+		w.Header().Set(k, v)
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	_, err := w.Write([]byte(r.Body))
+	if err != nil {
+		log.Errorf("internal error writing body: %v", err.Error())
+	}
+}
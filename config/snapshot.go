@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/gomicro/ledger"
+	"gopkg.in/yaml.v2"
+)
+
+// Snapshot represents the runtime state of Avenues: every route, including
+// those added by discovery or the routes endpoint, where each came from, the
+// position of each ordinal route, and the active faults
+type Snapshot struct {
+	Routes  map[string]*Route `yaml:"routes"`
+	Sources map[string]string `yaml:"sources,omitempty"`
+	Indices map[string]int    `yaml:"indices,omitempty"`
+	Faults  []*Fault          `yaml:"faults,omitempty"`
+}
+
+// Snapshot captures the current runtime state
+func (f *File) Snapshot() *Snapshot {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	s := &Snapshot{
+		Routes:  make(map[string]*Route, len(f.Routes)),
+		Sources: make(map[string]string),
+		Indices: make(map[string]int),
+		Faults:  f.faults.list(),
+	}
+
+	for prefix, route := range f.Routes {
+		s.Routes[prefix] = route
+
+		if source, ok := f.sources[prefix]; ok {
+			s.Sources[prefix] = source
+		}
+
+		if strings.ToLower(route.Type) == ordinalRouteType {
+			route.mu.Lock()
+			s.Indices[prefix] = route.index
+			route.mu.Unlock()
+		}
+	}
+
+	return s
+}
+
+// Restore replaces the runtime state with the snapshot. Every route and fault
+// is loaded before anything is replaced, so a snapshot that fails to load
+// leaves the state untouched. Routes configured the same as the current ones
+// keep their hits and errors.
+func (f *File) Restore(s *Snapshot) error {
+	for prefix, route := range s.Routes {
+		if route == nil {
+			return fmt.Errorf("route '%v' is empty", prefix)
+		}
+
+		err := f.loadRoute(route)
+		if err != nil {
+			return fmt.Errorf("failed to load route '%v': %v", prefix, err.Error())
+		}
+	}
+
+	for prefix, i := range s.Indices {
+		route, ok := s.Routes[prefix]
+		if !ok || strings.ToLower(route.Type) != ordinalRouteType {
+			return fmt.Errorf("index given for '%v', which is not an ordinal route", prefix)
+		}
+
+		if i < 0 || i >= len(route.Backends) {
+			return fmt.Errorf("index %v out of range for '%v'", i, prefix)
+		}
+	}
+
+	for _, fault := range s.Faults {
+		err := fault.init()
+		if err != nil {
+			return fmt.Errorf("failed to load fault: %v", err.Error())
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	routes := make(map[string]*Route, len(s.Routes))
+	for prefix, route := range s.Routes {
+		if existing, ok := f.Routes[prefix]; ok && sameRoute(existing, route) {
+			route = existing
+		}
+
+		route.mu.Lock()
+		route.index = s.Indices[prefix]
+		route.mu.Unlock()
+
+		routes[prefix] = route
+	}
+
+	sources := make(map[string]string, len(s.Sources))
+	for prefix, source := range s.Sources {
+		if _, ok := routes[prefix]; ok && source != configSource {
+			sources[prefix] = source
+		}
+	}
+
+	f.Routes = routes
+	f.sources = sources
+	f.faults.replace(s.Faults)
+
+	log.Infof("restored snapshot of %v routes and %v faults", len(routes), len(s.Faults))
+
+	return nil
+}
+
+func (f *File) handleSnapshot(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		b, err := yaml.Marshal(f.Snapshot())
+		if err != nil {
+			log.Errorf("internal error marshalling snapshot: %v", err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-yaml")
+		w.WriteHeader(http.StatusOK)
+
+		_, err = w.Write(b)
+		if err != nil {
+			log.Errorf("internal error writing snapshot: %v", err.Error())
+		}
+	case http.MethodPut, http.MethodPost:
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read snapshot: %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		var s Snapshot
+		err = yaml.Unmarshal(b, &s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to unmarshal snapshot: %v", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err = f.Restore(&s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		prefixes := make([]string, 0, len(s.Routes))
+		for prefix := range s.Routes {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"routes": prefixes, "faults": len(s.Faults)})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
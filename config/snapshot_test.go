@@ -0,0 +1,161 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSnapshot(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	newBackend := func(status int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+	}
+
+	first := newBackend(http.StatusOK)
+	defer first.Close()
+
+	second := newBackend(http.StatusAccepted)
+	defer second.Close()
+
+	g.Describe("Snapshots", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+  /v1/posts:
+    type: ordinal
+    backends:
+      - %v
+      - %v
+`, first.URL, first.URL, second.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		do := func(s *httptest.Server, method, path, body string) (int, string) {
+			req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+			Expect(err).To(BeNil())
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			Expect(err).To(BeNil())
+
+			return resp.StatusCode, string(b)
+		}
+
+		g.It("should export and restore the runtime state", func() {
+			status, _ := do(admin, http.MethodPut, "/avenues/routes/v1/teams", fmt.Sprintf(`{"backend": %q}`, second.URL))
+			Expect(status).To(Equal(http.StatusCreated))
+
+			status, _ = do(server, http.MethodGet, "/v1/posts", "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _ = do(admin, http.MethodPost, "/avenues/faults", `{"route": "/v1/users", "down": true}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, baseline := do(admin, http.MethodGet, "/avenues/snapshot", "")
+			Expect(status).To(Equal(http.StatusOK))
+
+			var s config.Snapshot
+			Expect(yaml.Unmarshal([]byte(baseline), &s)).To(BeNil())
+			Expect(s.Routes).To(HaveLen(3))
+			Expect(s.Routes["/v1/teams"].Backend).To(Equal(second.URL))
+			Expect(s.Sources).To(Equal(map[string]string{"/v1/teams": "admin"}))
+			Expect(s.Indices).To(Equal(map[string]int{"/v1/posts": 1}))
+			Expect(s.Faults).To(HaveLen(1))
+			Expect(s.Faults[0].Route).To(Equal("/v1/users"))
+
+			// wander away from the baseline
+			do(admin, http.MethodDelete, "/avenues/routes/v1/teams", "")
+			do(admin, http.MethodDelete, "/avenues/faults", "")
+			do(admin, http.MethodPut, "/avenues/routes/v1/extra", fmt.Sprintf(`{"backend": %q}`, first.URL))
+			do(admin, http.MethodPost, "/avenues/reset", "")
+
+			status, _ = do(server, http.MethodGet, "/v1/teams", "")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			status, body := do(admin, http.MethodPut, "/avenues/snapshot", baseline)
+			Expect(status).To(Equal(http.StatusOK))
+
+			var restored struct {
+				Routes []string `json:"routes"`
+				Faults int      `json:"faults"`
+			}
+			Expect(json.Unmarshal([]byte(body), &restored)).To(BeNil())
+			Expect(restored.Routes).To(Equal([]string{"/v1/posts", "/v1/teams", "/v1/users"}))
+			Expect(restored.Faults).To(Equal(1))
+
+			status, _ = do(server, http.MethodGet, "/v1/teams", "")
+			Expect(status).To(Equal(http.StatusAccepted))
+
+			status, _ = do(server, http.MethodGet, "/v1/extra", "")
+			Expect(status).To(Equal(http.StatusNotFound))
+
+			status, _ = do(server, http.MethodGet, "/v1/users", "")
+			Expect(status).To(Equal(http.StatusServiceUnavailable))
+
+			status, _ = do(server, http.MethodGet, "/v1/posts", "")
+			Expect(status).To(Equal(http.StatusAccepted))
+
+			_, routes := do(admin, http.MethodGet, "/avenues/routes/v1/posts", "")
+			Expect(routes).To(ContainSubstring(`"hits":2`))
+			Expect(routes).To(ContainSubstring(`"source":"config"`))
+		})
+
+		g.It("should leave the state untouched when a snapshot fails to load", func() {
+			status, _ := do(admin, http.MethodPut, "/avenues/snapshot", `
+routes:
+  /v1/other:
+    type: redirect
+`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = do(admin, http.MethodPut, "/avenues/snapshot", fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+indices:
+  /v1/users: 1
+`, first.URL))
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = do(admin, http.MethodPut, "/avenues/snapshot", `
+routes: {}
+faults:
+  - latency: 1s
+`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = do(server, http.MethodGet, "/v1/users", "")
+			Expect(status).To(Equal(http.StatusOK))
+		})
+	})
+}
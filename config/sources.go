@@ -0,0 +1,81 @@
+package config
+
+import (
+	"bytes"
+
+	log "github.com/gomicro/ledger"
+	"gopkg.in/yaml.v2"
+)
+
+// Discovery represents the providers that add and remove routes while
+// Avenues is running
+type Discovery struct {
+	Docker string `yaml:"docker,omitempty"`
+}
+
+// SyncRoutes replaces the routes previously provided by the named source with
+// the given routes, removing any the source no longer provides. Routes from
+// the config file, or from another source, are never replaced, and routes that
+// fail to load are skipped.
+func (f *File) SyncRoutes(source string, routes map[string]*Route) {
+	loaded := map[string]*Route{}
+	for prefix, route := range routes {
+		err := f.loadRoute(route)
+		if err != nil {
+			log.Warnf("skipping route '%v' from %v: %v", prefix, source, err.Error())
+			continue
+		}
+
+		loaded[prefix] = route
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for prefix, owner := range f.sources {
+		if owner != source {
+			continue
+		}
+
+		if _, ok := loaded[prefix]; !ok {
+			delete(f.Routes, prefix)
+			delete(f.sources, prefix)
+			log.Infof("removed route '%v' from %v", prefix, source)
+		}
+	}
+
+	for prefix, route := range loaded {
+		if _, exists := f.Routes[prefix]; exists && f.sources[prefix] != source {
+			log.Warnf("skipping route '%v' from %v: route already exists", prefix, source)
+			continue
+		}
+
+		existing, exists := f.Routes[prefix]
+		if exists && sameRoute(existing, route) {
+			continue
+		}
+
+		if !exists {
+			log.Infof("added route '%v' from %v", prefix, source)
+		}
+
+		f.Routes[prefix] = route
+		f.sources[prefix] = source
+	}
+}
+
+// sameRoute reports whether two routes are configured identically, so an
+// unchanged route keeps its runtime state.
+func sameRoute(a, b *Route) bool {
+	ab, err := yaml.Marshal(a)
+	if err != nil {
+		return false
+	}
+
+	bb, err := yaml.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(ab, bb)
+}
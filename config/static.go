@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/gomicro/ledger"
+)
+
+const indexFile = "index.html"
+
+func loadStaticDir(route *Route) error {
+	if route.Dir == "" {
+		return fmt.Errorf("static_dir route requires dir directive")
+	}
+
+	fi, err := os.Stat(route.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to find dir: %v", err.Error())
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("dir is not a directory: %v", route.Dir)
+	}
+
+	return nil
+}
+
+// serveDir answers the request with a file from the route's directory. When
+// the route is a single page app, requests for missing paths without an
+// extension are answered with the directory's index.
+func serveDir(w http.ResponseWriter, req *http.Request, prefix string, route *Route) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	rel := path.Clean("/" + strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(prefix, "/")))
+	name := filepath.Join(route.Dir, filepath.FromSlash(rel))
+
+	fi, err := os.Stat(name)
+	if err == nil && fi.IsDir() {
+		name = filepath.Join(name, indexFile)
+		fi, err = os.Stat(name)
+	}
+
+	if err != nil && route.SPA && os.IsNotExist(err) && path.Ext(rel) == "" {
+		name = filepath.Join(route.Dir, indexFile)
+		fi, err = os.Stat(name)
+	}
+
+	if err != nil {
+		log.Warnf("failed to serve file: %v", err.Error())
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		log.Errorf("failed to open file: %v", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()))
+	http.ServeContent(w, req, fi.Name(), fi.ModTime(), file)
+	log.Infof("served '%v' from '%v'", req.URL, name)
+}
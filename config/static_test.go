@@ -0,0 +1,127 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestStaticDir(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Static Directories", func() {
+		var dir string
+		var server *httptest.Server
+
+		g.BeforeEach(func() {
+			d, err := ioutil.TempDir("", "static")
+			Expect(err).To(BeNil())
+			dir = d
+
+			Expect(os.Mkdir(filepath.Join(dir, "js"), 0755)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("console.log('app')"), 0644)).To(BeNil())
+
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /app:
+    type: static_dir
+    dir: %v
+    spa: true
+`, dir)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			os.RemoveAll(dir)
+		})
+
+		g.It("should serve files with content types and validators", func() {
+			res, err := http.Get(server.URL + "/app/js/app.js")
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(b)).To(Equal("console.log('app')"))
+			Expect(res.Header.Get("Content-Type")).To(ContainSubstring("javascript"))
+			Expect(res.Header.Get("ETag")).NotTo(BeEmpty())
+			Expect(res.Header.Get("Last-Modified")).NotTo(BeEmpty())
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/app/js/app.js", nil)
+			Expect(err).To(BeNil())
+			req.Header.Set("If-None-Match", res.Header.Get("ETag"))
+
+			res, err = http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			res.Body.Close()
+			Expect(res.StatusCode).To(Equal(http.StatusNotModified))
+		})
+
+		g.It("should serve range requests", func() {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/app/js/app.js", nil)
+			Expect(err).To(BeNil())
+			req.Header.Set("Range", "bytes=0-6")
+
+			res, err := http.DefaultClient.Do(req)
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusPartialContent))
+			Expect(string(b)).To(Equal("console"))
+		})
+
+		g.It("should serve the index for the root", func() {
+			res, err := http.Get(server.URL + "/app/")
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(string(b)).To(Equal("<html>app</html>"))
+		})
+
+		g.It("should fall back to the index for app paths", func() {
+			res, err := http.Get(server.URL + "/app/users/42")
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("Content-Type")).To(ContainSubstring("text/html"))
+			Expect(string(b)).To(Equal("<html>app</html>"))
+		})
+
+		g.It("should not fall back for missing assets", func() {
+			res, err := http.Get(server.URL + "/app/js/missing.js")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		g.It("should require an existing directory", func() {
+			_, err := config.Parse([]byte(`
+routes:
+  /app:
+    type: static_dir
+    dir: ./does_not_exist
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
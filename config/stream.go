@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/gomicro/ledger"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+// handleStream sends each request the journal records, and the matcher in
+// the query selects, to the client as a server-sent event until it
+// disconnects.
+func (f *File) handleStream(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	m, err := parseRequestMatcher(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := f.journal.subscribe()
+	defer f.journal.unsubscribe(sub)
+
+	setCORSHeaders(w.Header())
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = fmt.Fprint(w, ": connected\n\n")
+	if err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e := <-sub:
+			if !m.matches(e) {
+				continue
+			}
+
+			b, err := json.Marshal(e)
+			if err != nil {
+				log.Errorf("failed to marshal journal entry: %v", err.Error())
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "id: %v\nevent: request\ndata: %s\n\n", e.ID, b)
+			if err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+		case <-req.Context().Done():
+			return
+		}
+
+		flusher.Flush()
+	}
+}
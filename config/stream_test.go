@@ -0,0 +1,141 @@
+package config_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestStream(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	g.Describe("Streaming traffic", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+  /v1/teams:
+    backend: %v
+`, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		// stream connects to the stream endpoint and sends each event's id and
+		// data on the returned channel
+		stream := func(query string) (chan [2]string, func()) {
+			resp, err := http.Get(admin.URL + "/avenues/stream?" + query)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			events := make(chan [2]string, 10)
+			go func() {
+				var id string
+				scanner := bufio.NewScanner(resp.Body)
+				for scanner.Scan() {
+					line := scanner.Text()
+					switch {
+					case strings.HasPrefix(line, "id: "):
+						id = strings.TrimPrefix(line, "id: ")
+					case strings.HasPrefix(line, "data: "):
+						events <- [2]string{id, strings.TrimPrefix(line, "data: ")}
+					}
+				}
+				close(events)
+			}()
+
+			return events, func() { resp.Body.Close() }
+		}
+
+		get := func(path string) {
+			resp, err := http.Get(server.URL + path)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+		}
+
+		next := func(events chan [2]string) (string, *config.JournalEntry) {
+			select {
+			case ev := <-events:
+				var e config.JournalEntry
+				Expect(json.Unmarshal([]byte(ev[1]), &e)).To(BeNil())
+				return ev[0], &e
+			case <-time.After(2 * time.Second):
+				g.Fail("timed out waiting for event")
+			}
+
+			return "", nil
+		}
+
+		g.It("should stream each completed request", func() {
+			events, stop := stream("")
+			defer stop()
+
+			get("/v1/users/1")
+			get("/v1/teams/1")
+
+			id, e := next(events)
+			Expect(id).To(Equal("1"))
+			Expect(e.Path).To(Equal("/v1/users/1"))
+			Expect(e.Route).To(Equal("/v1/users"))
+			Expect(e.Status).To(Equal(http.StatusOK))
+
+			id, e = next(events)
+			Expect(id).To(Equal("2"))
+			Expect(e.Path).To(Equal("/v1/teams/1"))
+		})
+
+		g.It("should filter the stream by route and status", func() {
+			events, stop := stream("route=/v1/users&status=4xx")
+			defer stop()
+
+			get("/v1/teams/missing")
+			get("/v1/users/1")
+			get("/v1/users/missing")
+
+			_, e := next(events)
+			Expect(e.Path).To(Equal("/v1/users/missing"))
+			Expect(e.Status).To(Equal(http.StatusNotFound))
+
+			Consistently(events, 100*time.Millisecond).ShouldNot(Receive())
+		})
+
+		g.It("should reject invalid filters", func() {
+			resp, err := http.Get(admin.URL + "/avenues/stream?status=abc")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+var errTruncated = errors.New("response body truncated by throttle")
+
+// Throttle represents the bandwidth limits and trickle behaviours applied to
+// the traffic passing through a route. Rates are in bytes per second.
+type Throttle struct {
+	Upload    int           `yaml:"upload,omitempty"`
+	Download  int           `yaml:"download,omitempty"`
+	FirstByte time.Duration `yaml:"first_byte,omitempty"`
+	Truncate  int           `yaml:"truncate,omitempty"`
+}
+
+func (t *Throttle) reader(body io.ReadCloser) io.ReadCloser {
+	if t.Upload <= 0 || body == nil || body == http.NoBody {
+		return body
+	}
+
+	return &throttledReader{ReadCloser: body, rate: t.Upload}
+}
+
+func (t *Throttle) writer(w http.ResponseWriter) http.ResponseWriter {
+	if t.Download <= 0 && t.Truncate <= 0 && t.FirstByte <= 0 {
+		return w
+	}
+
+	remaining := -1
+	if t.Truncate > 0 {
+		remaining = t.Truncate
+	}
+
+	return &throttledWriter{ResponseWriter: w, rate: t.Download, remaining: remaining, firstByte: t.FirstByte}
+}
+
+type throttledReader struct {
+	io.ReadCloser
+	rate int
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	c := chunkSize(r.rate)
+	if len(p) > c {
+		p = p[:c]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	pause(n, r.rate)
+
+	return n, err
+}
+
+type throttledWriter struct {
+	http.ResponseWriter
+	rate      int
+	remaining int
+	firstByte time.Duration
+	started   bool
+}
+
+// start holds the response for the first byte delay before anything is sent
+func (w *throttledWriter) start() {
+	if w.started {
+		return
+	}
+
+	w.started = true
+	time.Sleep(w.firstByte)
+}
+
+func (w *throttledWriter) WriteHeader(status int) {
+	w.start()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	w.start()
+
+	truncated := false
+	if w.remaining >= 0 {
+		if len(p) > w.remaining {
+			p = p[:w.remaining]
+			truncated = true
+		}
+		w.remaining -= len(p)
+	}
+
+	written := 0
+	for len(p) > 0 {
+		c := len(p)
+		if w.rate > 0 && c > chunkSize(w.rate) {
+			c = chunkSize(w.rate)
+		}
+
+		pause(c, w.rate)
+
+		n, err := w.ResponseWriter.Write(p[:c])
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		w.Flush()
+		p = p[c:]
+	}
+
+	if truncated {
+		return written, errTruncated
+	}
+
+	return written, nil
+}
+
+// Flush sends any buffered data to the client so throttled chunks are
+// delivered as they are written.
+func (w *throttledWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection
+func (w *throttledWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+
+	return hj.Hijack()
+}
+
+// chunkSize is the amount of bytes moved at once, sized to roughly a tenth
+// of a second worth of traffic at the given rate.
+func chunkSize(rate int) int {
+	c := rate / 10
+	if c < 1 {
+		c = 1
+	}
+
+	return c
+}
+
+func pause(n, rate int) {
+	if n <= 0 || rate <= 0 {
+		return
+	}
+
+	time.Sleep(time.Duration(n) * time.Second / time.Duration(rate))
+}
@@ -0,0 +1,128 @@
+package config_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestThrottle(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	body := strings.Repeat("a", 200)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if len(b) > 0 {
+			_, _ = w.Write(b)
+			return
+		}
+
+		_, _ = w.Write([]byte(body))
+	}))
+	defer backend.Close()
+
+	g.Describe("Throttling", func() {
+		g.It("should limit the download rate", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/slow:
+    backend: %v
+    throttle:
+      download: 1000
+`, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			start := time.Now()
+			res, err := http.Get(server.URL + "/v1/slow")
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			b, err := ioutil.ReadAll(res.Body)
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(body))
+			Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		})
+
+		g.It("should limit the upload rate", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/slow:
+    backend: %v
+    throttle:
+      upload: 1000
+`, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			start := time.Now()
+			res, err := http.Post(server.URL+"/v1/slow", "text/plain", bytes.NewBufferString(body))
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			b, err := ioutil.ReadAll(res.Body)
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(body))
+			Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		})
+
+		g.It("should delay the first byte", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/slow:
+    backend: %v
+    throttle:
+      first_byte: 250ms
+`, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			start := time.Now()
+			res, err := http.Get(server.URL + "/v1/slow")
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			Expect(time.Since(start)).To(BeNumerically(">=", 250*time.Millisecond))
+		})
+
+		g.It("should truncate the body", func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/slow:
+    backend: %v
+    throttle:
+      truncate: 50
+`, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server := httptest.NewServer(c)
+			defer server.Close()
+
+			res, err := http.Get(server.URL + "/v1/slow")
+			Expect(err).To(BeNil())
+			defer res.Body.Close()
+
+			b, err := ioutil.ReadAll(res.Body)
+			Expect(err).NotTo(BeNil())
+			Expect(len(b)).To(Equal(50))
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gomicro/avenues/openapi"
+	log "github.com/gomicro/ledger"
+)
+
+const (
+	logValidation    = "log"
+	headerValidation = "header"
+	rejectValidation = "reject"
+
+	validationHeader = "X-Avenues-Validation"
+)
+
+// Validation represents checking the traffic of a route against an OpenAPI
+// spec. Violations are always logged, and depending on the mode are also
+// added to the response as warning headers or cause the exchange to be
+// rejected.
+type Validation struct {
+	Spec string            `yaml:"spec"`
+	Mode string            `yaml:"mode,omitempty"`
+	spec *openapi.Document `yaml:"-"`
+}
+
+type violationReport struct {
+	Source     string   `json:"source"`
+	Violations []string `json:"violations"`
+}
+
+func loadValidation(v *Validation) error {
+	if v.Spec == "" {
+		return fmt.Errorf("openapi validation requires spec directive")
+	}
+
+	switch v.Mode {
+	case "":
+		v.Mode = logValidation
+	case logValidation, headerValidation, rejectValidation:
+	default:
+		return fmt.Errorf("unknown validation mode: %v", v.Mode)
+	}
+
+	doc, err := openapi.Load(v.Spec)
+	if err != nil {
+		return err
+	}
+
+	v.spec = doc
+
+	return nil
+}
+
+// request validates the request against the spec, returning true when the
+// request has been rejected and must not be proxied.
+func (v *Validation) request(w http.ResponseWriter, req *http.Request) bool {
+	body, err := readBody(req)
+	if err != nil {
+		log.Warnf("failed to validate request: %v", err.Error())
+		return false
+	}
+
+	violations := v.spec.ValidateRequest(req, body)
+	if len(violations) == 0 {
+		return false
+	}
+
+	logViolations("request", req, violations)
+
+	switch v.Mode {
+	case headerValidation:
+		for _, violation := range violations {
+			w.Header().Add(validationHeader, fmt.Sprintf("request: %v", violation))
+		}
+	case rejectValidation:
+		writeJSON(w, http.StatusBadRequest, &violationReport{Source: "request", Violations: violations})
+		return true
+	}
+
+	return false
+}
+
+// response validates the backend's response to the request against the spec.
+// Rejected responses are replaced with a 502 describing the violations.
+func (v *Validation) response(req *http.Request, resp *http.Response) error {
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err.Error())
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	// Encoded bodies cannot be inspected, so only the status is checked
+	body := b
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		body = nil
+	}
+
+	violations := v.spec.ValidateResponse(req, resp.StatusCode, resp.Header, body)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	logViolations("response", req, violations)
+
+	switch v.Mode {
+	case headerValidation:
+		for _, violation := range violations {
+			resp.Header.Add(validationHeader, fmt.Sprintf("response: %v", violation))
+		}
+	case rejectValidation:
+		report, err := json.Marshal(&violationReport{Source: "response", Violations: violations})
+		if err != nil {
+			return fmt.Errorf("failed to marshal violations: %v", err.Error())
+		}
+
+		resp.StatusCode = http.StatusBadGateway
+		resp.Status = http.StatusText(http.StatusBadGateway)
+		resp.Header = http.Header{}
+		resp.Header.Set("Content-Type", "application/json")
+		resp.Header.Set("Content-Length", strconv.Itoa(len(report)))
+		resp.ContentLength = int64(len(report))
+		resp.Body = ioutil.NopCloser(bytes.NewReader(report))
+	}
+
+	return nil
+}
+
+func logViolations(source string, req *http.Request, violations []string) {
+	for _, violation := range violations {
+		log.Warnf("openapi %v violation for '%v %v': %v", source, req.Method, req.URL.Path, violation)
+	}
+}
@@ -0,0 +1,138 @@
+package config_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidation(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/pets":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 1, "name": "Rex"}`))
+		case "/v1/pets/1":
+			_, _ = w.Write([]byte(`{"id": "one"}`))
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer backend.Close()
+
+	serve := func(mode string) *httptest.Server {
+		c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/pets:
+    backend: %v
+    openapi:
+      spec: ./petstore.yaml
+      mode: %v
+`, backend.URL, mode)))
+		Expect(err).To(BeNil())
+
+		return httptest.NewServer(c)
+	}
+
+	g.Describe("OpenAPI Validation", func() {
+		g.It("should pass valid exchanges untouched", func() {
+			server := serve("reject")
+			defer server.Close()
+
+			res, err := http.Post(server.URL+"/v1/pets", "application/json", bytes.NewBufferString(`{"name": "Rex"}`))
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(res.Header.Get("X-Avenues-Validation")).To(BeEmpty())
+		})
+
+		g.It("should only log violations by default", func() {
+			server := serve("log")
+			defer server.Close()
+
+			res, err := http.Post(server.URL+"/v1/pets", "application/json", bytes.NewBufferString(`{"tag": 1}`))
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(res.Header.Get("X-Avenues-Validation")).To(BeEmpty())
+		})
+
+		g.It("should add warning headers for violations", func() {
+			server := serve("header")
+			defer server.Close()
+
+			res, err := http.Post(server.URL+"/v1/pets", "application/json", bytes.NewBufferString(`{"tag": 1}`))
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(res.Header.Values("X-Avenues-Validation")).To(ConsistOf(
+				"request: request body.name is required",
+				"request: request body.tag must be a string",
+			))
+
+			res, err = http.Get(server.URL + "/v1/pets/1")
+			Expect(err).To(BeNil())
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Values("X-Avenues-Validation")).To(ConsistOf(
+				"response: response body.id must be of type integer",
+				"response: response body.name is required",
+			))
+		})
+
+		g.It("should reject invalid requests", func() {
+			server := serve("reject")
+			defer server.Close()
+
+			res, err := http.Get(server.URL + "/v1/pets?limit=lots")
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(b).To(MatchJSON(`{"source": "request", "violations": ["query parameter limit must be of type integer"]}`))
+		})
+
+		g.It("should reject invalid responses", func() {
+			server := serve("reject")
+			defer server.Close()
+
+			res, err := http.Get(server.URL + "/v1/pets/2")
+			Expect(err).To(BeNil())
+			b, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(b).To(MatchJSON(`{"source": "response", "violations": ["response status 418 is not defined"]}`))
+		})
+
+		g.It("should reject unknown modes", func() {
+			_, err := config.Parse([]byte(`
+routes:
+  /v1/pets:
+    backend: http://pets:4567
+    openapi:
+      spec: ./petstore.yaml
+      mode: shout
+`))
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
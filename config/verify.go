@@ -0,0 +1,204 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/gomicro/ledger"
+)
+
+const nearMissLimit = 10
+
+// Expectation represents how many requests matching Request a test expects
+// Avenues to have handled. It defaults to at least one. A Timeout, such as
+// "5s", waits for the expectation to be met.
+type Expectation struct {
+	Request RequestMatcher `json:"request"`
+	Exactly *int           `json:"exactly,omitempty"`
+	AtLeast *int           `json:"at_least,omitempty"`
+	AtMost  *int           `json:"at_most,omitempty"`
+	Timeout string         `json:"timeout,omitempty"`
+	timeout time.Duration
+}
+
+// verification reports how an expectation compares with the journal
+type verification struct {
+	Satisfied  bool            `json:"satisfied"`
+	Expected   string          `json:"expected"`
+	Count      int             `json:"count"`
+	Matched    []*JournalEntry `json:"matched"`
+	NearMisses []*nearMiss     `json:"near_misses,omitempty"`
+}
+
+// nearMiss is a request that failed to match an expectation and why
+type nearMiss struct {
+	Request    *JournalEntry `json:"request"`
+	Mismatches []string      `json:"mismatches"`
+}
+
+func (e *Expectation) load() error {
+	err := e.Request.validate()
+	if err != nil {
+		return err
+	}
+
+	if e.Exactly != nil && (e.AtLeast != nil || e.AtMost != nil) {
+		return fmt.Errorf("exactly cannot be combined with at_least or at_most")
+	}
+
+	for _, n := range []*int{e.Exactly, e.AtLeast, e.AtMost} {
+		if n != nil && *n < 0 {
+			return fmt.Errorf("expected counts must not be negative")
+		}
+	}
+
+	if e.Exactly == nil && e.AtLeast == nil && e.AtMost == nil {
+		one := 1
+		e.AtLeast = &one
+	}
+
+	if e.Timeout != "" {
+		e.timeout, err = time.ParseDuration(e.Timeout)
+		if err != nil {
+			return fmt.Errorf("failed to parse timeout: %v", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (e *Expectation) bounds() (int, int) {
+	if e.Exactly != nil {
+		return *e.Exactly, *e.Exactly
+	}
+
+	min, max := 0, -1
+	if e.AtLeast != nil {
+		min = *e.AtLeast
+	}
+
+	if e.AtMost != nil {
+		max = *e.AtMost
+	}
+
+	return min, max
+}
+
+func (e *Expectation) String() string {
+	if e.Exactly != nil {
+		return fmt.Sprintf("exactly %v", *e.Exactly)
+	}
+
+	switch {
+	case e.AtLeast != nil && e.AtMost != nil:
+		return fmt.Sprintf("between %v and %v", *e.AtLeast, *e.AtMost)
+	case e.AtMost != nil:
+		return fmt.Sprintf("at most %v", *e.AtMost)
+	}
+
+	return fmt.Sprintf("at least %v", *e.AtLeast)
+}
+
+// verify compares the expectation with the journal
+func (e *Expectation) verify(entries []*JournalEntry) *verification {
+	v := &verification{
+		Expected: e.String(),
+		Matched:  []*JournalEntry{},
+	}
+
+	var misses []*nearMiss
+	for _, entry := range entries {
+		mm := e.Request.mismatches(entry)
+		if len(mm) == 0 {
+			v.Matched = append(v.Matched, entry)
+			continue
+		}
+
+		misses = append(misses, &nearMiss{Request: entry, Mismatches: mm})
+	}
+
+	v.Count = len(v.Matched)
+
+	min, max := e.bounds()
+	v.Satisfied = v.Count >= min && (max < 0 || v.Count <= max)
+
+	if !v.Satisfied {
+		// the closest misses, and the most recent among equals, come first
+		sort.SliceStable(misses, func(i, j int) bool {
+			if len(misses[i].Mismatches) != len(misses[j].Mismatches) {
+				return len(misses[i].Mismatches) < len(misses[j].Mismatches)
+			}
+
+			return misses[i].Request.ID > misses[j].Request.ID
+		})
+
+		if len(misses) > nearMissLimit {
+			misses = misses[:nearMissLimit]
+		}
+
+		v.NearMisses = misses
+	}
+
+	return v
+}
+
+// exceeded reports whether more requests matched than the expectation allows,
+// which waiting cannot fix
+func (e *Expectation) exceeded(v *verification) bool {
+	_, max := e.bounds()
+	return max >= 0 && v.Count > max
+}
+
+// handleVerify checks an expectation against the journal, waiting up to its
+// timeout for it to be met. Unmet expectations are answered with a 417
+// describing the requests that came closest.
+func (f *File) handleVerify(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var e Expectation
+	err := json.NewDecoder(req.Body).Decode(&e)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode expectation: %v", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = e.load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+
+	for {
+		changed := f.journal.watch()
+
+		v := e.verify(f.journal.all())
+		if v.Satisfied {
+			writeJSON(w, http.StatusOK, v)
+			return
+		}
+
+		if e.exceeded(v) {
+			writeJSON(w, http.StatusExpectationFailed, v)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			log.Infof("expectation of %v requests unmet after %v", v.Expected, e.timeout)
+			writeJSON(w, http.StatusExpectationFailed, v)
+			return
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
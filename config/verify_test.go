@@ -0,0 +1,153 @@
+package config_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+type verification struct {
+	Satisfied  bool                   `json:"satisfied"`
+	Expected   string                 `json:"expected"`
+	Count      int                    `json:"count"`
+	Matched    []*config.JournalEntry `json:"matched"`
+	NearMisses []struct {
+		Request    *config.JournalEntry `json:"request"`
+		Mismatches []string             `json:"mismatches"`
+	} `json:"near_misses"`
+}
+
+func TestVerify(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	g.Describe("Verifying expectations", func() {
+		var server, admin *httptest.Server
+
+		g.BeforeEach(func() {
+			c, err := config.Parse([]byte(fmt.Sprintf(`
+routes:
+  /v1/users:
+    backend: %v
+  /v1/teams:
+    backend: %v
+`, backend.URL, backend.URL)))
+			Expect(err).To(BeNil())
+
+			server = httptest.NewServer(c)
+			admin = httptest.NewServer(c.AdminHandler())
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			admin.Close()
+		})
+
+		get := func(path string) {
+			resp, err := http.Get(server.URL + path)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+		}
+
+		verify := func(body string) (int, *verification) {
+			resp, err := http.Post(admin.URL+"/avenues/verify", "application/json", strings.NewReader(body))
+			Expect(err).To(BeNil())
+			defer resp.Body.Close()
+
+			if resp.StatusCode == http.StatusBadRequest {
+				return resp.StatusCode, nil
+			}
+
+			var v verification
+			Expect(json.NewDecoder(resp.Body).Decode(&v)).To(BeNil())
+
+			return resp.StatusCode, &v
+		}
+
+		g.It("should check counts of matching requests", func() {
+			get("/v1/users/1")
+			get("/v1/users/2")
+			get("/v1/teams/1")
+
+			status, v := verify(`{"request": {"route": "/v1/users"}, "exactly": 2}`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(v.Satisfied).To(BeTrue())
+			Expect(v.Expected).To(Equal("exactly 2"))
+			Expect(v.Count).To(Equal(2))
+			Expect(v.Matched[1].Path).To(Equal("/v1/users/2"))
+			Expect(v.NearMisses).To(BeEmpty())
+
+			status, _ = verify(`{"request": {"path": "/v1/teams/{id}"}}`)
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _ = verify(`{"request": {"method": "GET"}, "at_least": 2, "at_most": 3}`)
+			Expect(status).To(Equal(http.StatusOK))
+		})
+
+		g.It("should report mismatches", func() {
+			get("/v1/users/1")
+			get("/v1/teams/1")
+
+			status, v := verify(`{"request": {"route": "/v1/users", "method": "POST"}, "at_least": 1}`)
+			Expect(status).To(Equal(http.StatusExpectationFailed))
+			Expect(v.Satisfied).To(BeFalse())
+			Expect(v.Count).To(Equal(0))
+			Expect(v.NearMisses).To(HaveLen(2))
+			Expect(v.NearMisses[0].Request.Path).To(Equal("/v1/users/1"))
+			Expect(v.NearMisses[0].Mismatches).To(Equal([]string{"method: expected POST, got GET"}))
+			Expect(v.NearMisses[1].Mismatches).To(HaveLen(2))
+
+			status, v = verify(`{"request": {"route": "/v1/users"}, "at_most": 0, "timeout": "5s"}`)
+			Expect(status).To(Equal(http.StatusExpectationFailed))
+			Expect(v.Expected).To(Equal("at most 0"))
+		})
+
+		g.It("should wait for expectations to be met", func() {
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				get("/v1/users/1")
+				time.Sleep(100 * time.Millisecond)
+				get("/v1/users/2")
+			}()
+
+			start := time.Now()
+			status, v := verify(`{"request": {"route": "/v1/users"}, "exactly": 2, "timeout": "5s"}`)
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(v.Count).To(Equal(2))
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+
+			start = time.Now()
+			status, _ = verify(`{"request": {"route": "/v1/teams"}, "timeout": "200ms"}`)
+			Expect(status).To(Equal(http.StatusExpectationFailed))
+			Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+		})
+
+		g.It("should reject invalid expectations", func() {
+			status, _ := verify(`{"exactly": 1, "at_least": 1}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = verify(`{"at_least": -1}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = verify(`{"timeout": "soon"}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+
+			status, _ = verify(`{"request": {"status": "bad"}}`)
+			Expect(status).To(Equal(http.StatusBadRequest))
+		})
+	})
+}
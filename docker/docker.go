@@ -0,0 +1,266 @@
+// Package docker discovers routes from the avenues labels of running
+// containers through the Docker Engine API.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+	"github.com/gomicro/avenues/labels"
+	log "github.com/gomicro/ledger"
+)
+
+const (
+	source     = "docker"
+	retryDelay = 2 * time.Second
+
+	// DefaultSocket is where the Docker Engine listens unless configured
+	// otherwise
+	DefaultSocket = "/var/run/docker.sock"
+)
+
+// Provider keeps the routes of a File in sync with the labelled containers
+// running on a Docker Engine
+type Provider struct {
+	baseURL string
+	client  *http.Client
+}
+
+type container struct {
+	ID              string            `json:"Id"`
+	Names           []string          `json:"Names"`
+	Labels          map[string]string `json:"Labels"`
+	Ports           []*port           `json:"Ports"`
+	NetworkSettings *networkSettings  `json:"NetworkSettings"`
+}
+
+type port struct {
+	PrivatePort int    `json:"PrivatePort"`
+	Type        string `json:"Type"`
+}
+
+type networkSettings struct {
+	Networks map[string]*network `json:"Networks"`
+}
+
+type network struct {
+	IPAddress string `json:"IPAddress"`
+}
+
+type event struct {
+	Action string `json:"Action"`
+	Actor  struct {
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+// New creates a Provider talking to the Docker Engine over the given unix
+// socket
+func New(socket string) *Provider {
+	if socket == "" {
+		socket = DefaultSocket
+	}
+
+	return NewWithClient("http://docker", &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	})
+}
+
+// NewWithClient creates a Provider talking to the Docker Engine API at the
+// given base URL using the client
+func NewWithClient(baseURL string, client *http.Client) *Provider {
+	return &Provider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Run syncs the routes of the labelled containers into the File, then keeps
+// them in sync as containers start and stop until the context is done.
+// Failures talking to the engine are logged and retried.
+func (p *Provider) Run(ctx context.Context, f *config.File) {
+	for {
+		err := p.Sync(ctx, f)
+		if err == nil {
+			err = p.watch(ctx, f)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warnf("docker discovery failed, retrying: %v", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// Sync replaces the routes previously discovered with those of the labelled
+// containers currently running.
+func (p *Provider) Sync(ctx context.Context, f *config.File) error {
+	containers, err := p.containers(ctx)
+	if err != nil {
+		return err
+	}
+
+	routes := map[string]*config.Route{}
+	for _, c := range containers {
+		cr, err := c.routes()
+		if err != nil {
+			log.Warnf("skipping container %v: %v", c.name(), err.Error())
+			continue
+		}
+
+		for prefix, route := range cr {
+			if _, ok := routes[prefix]; ok {
+				log.Warnf("skipping route '%v' from container %v: route already discovered", prefix, c.name())
+				continue
+			}
+
+			routes[prefix] = route
+		}
+	}
+
+	f.SyncRoutes(source, routes)
+
+	return nil
+}
+
+func (p *Provider) containers(ctx context.Context) ([]*container, error) {
+	filters := fmt.Sprintf(`{"label":[%q],"status":["running"]}`, labels.Route)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint("/containers/json", filters), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err.Error())
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list containers: %v", resp.Status)
+	}
+
+	var containers []*container
+	err = json.NewDecoder(resp.Body).Decode(&containers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode containers: %v", err.Error())
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].name() < containers[j].name()
+	})
+
+	return containers, nil
+}
+
+// watch follows the engine's event stream, syncing whenever a labelled
+// container changes state.
+func (p *Provider) watch(ctx context.Context, f *config.File) error {
+	filters := fmt.Sprintf(`{"type":["container"],"event":["start","stop","die","destroy","pause","unpause"],"label":[%q]}`, labels.Route)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint("/events", filters), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err.Error())
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to watch events: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to watch events: %v", resp.Status)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev event
+		err := dec.Decode(&ev)
+		if err != nil {
+			return fmt.Errorf("event stream ended: %v", err.Error())
+		}
+
+		log.Debugf("docker container %v: %v", ev.Actor.ID, ev.Action)
+
+		err = p.Sync(ctx, f)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Provider) endpoint(path, filters string) string {
+	return fmt.Sprintf("%v%v?filters=%v", p.baseURL, path, url.QueryEscape(filters))
+}
+
+func (c *container) name() string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+
+	return c.ID
+}
+
+// host returns the address of the container on the first of its networks,
+// falling back to its name for engines that do not report one.
+func (c *container) host() string {
+	if c.NetworkSettings != nil {
+		names := make([]string, 0, len(c.NetworkSettings.Networks))
+		for n := range c.NetworkSettings.Networks {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			if ip := c.NetworkSettings.Networks[n].IPAddress; ip != "" {
+				return ip
+			}
+		}
+	}
+
+	return c.name()
+}
+
+func (c *container) routes() (map[string]*config.Route, error) {
+	p, ok, err := labels.PortOf(c.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		for _, cp := range c.Ports {
+			if cp.Type == "" || cp.Type == "tcp" {
+				p, ok = cp.PrivatePort, true
+				break
+			}
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("no exposed ports and no %v label", labels.Port)
+	}
+
+	return labels.Routes(c.Labels, c.host(), p), nil
+}
@@ -0,0 +1,157 @@
+package docker_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+	"github.com/gomicro/avenues/docker"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+type fakeEngine struct {
+	sync.Mutex
+	containers []map[string]interface{}
+	events     chan string
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/containers/json":
+		e.Lock()
+		defer e.Unlock()
+
+		_ = json.NewEncoder(w).Encode(e.containers)
+	case "/events":
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case action := <-e.events:
+				fmt.Fprintf(w, `{"Type":"container","Action":%q,"Actor":{"ID":"abc"}}`+"\n", action)
+				w.(http.Flusher).Flush()
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (e *fakeEngine) set(containers ...map[string]interface{}) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.containers = containers
+}
+
+func TestDocker(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	bu, _ := url.Parse(backend.URL)
+	backendPort, _ := strconv.Atoi(bu.Port())
+
+	users := map[string]interface{}{
+		"Id":     "abc",
+		"Names":  []string{"/users"},
+		"Labels": map[string]string{"avenues.route": "/v1/users"},
+		"Ports":  []map[string]interface{}{{"PrivatePort": backendPort, "Type": "tcp"}},
+		"NetworkSettings": map[string]interface{}{
+			"Networks": map[string]interface{}{
+				"bridge": map[string]string{"IPAddress": "127.0.0.1"},
+			},
+		},
+	}
+
+	conflicting := map[string]interface{}{
+		"Id":     "def",
+		"Names":  []string{"/impostor"},
+		"Labels": map[string]string{"avenues.route": "/v1/static", "avenues.port": strconv.Itoa(backendPort)},
+	}
+
+	g.Describe("Docker Discovery", func() {
+		var engine *fakeEngine
+		var api *httptest.Server
+		var c *config.File
+		var server *httptest.Server
+
+		g.BeforeEach(func() {
+			engine = &fakeEngine{events: make(chan string)}
+			api = httptest.NewServer(engine)
+
+			f, err := config.Parse([]byte(`
+routes:
+  /v1/static:
+    backend: http://static:4567
+`))
+			Expect(err).To(BeNil())
+			c = f
+
+			server = httptest.NewServer(c)
+		})
+
+		g.AfterEach(func() {
+			server.Close()
+			api.CloseClientConnections()
+			api.Close()
+		})
+
+		status := func(path string) func() int {
+			return func() int {
+				res, err := http.Get(server.URL + path)
+				if err != nil {
+					return 0
+				}
+				res.Body.Close()
+
+				return res.StatusCode
+			}
+		}
+
+		g.It("should sync routes from labelled containers", func() {
+			engine.set(users, conflicting)
+
+			p := docker.NewWithClient(api.URL, http.DefaultClient)
+			Expect(p.Sync(context.Background(), c)).To(BeNil())
+
+			Expect(status("/v1/users")()).To(Equal(http.StatusAccepted))
+			Expect(status("/v1/static")()).To(Equal(http.StatusBadGateway))
+		})
+
+		g.It("should add and remove routes as containers start and stop", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			p := docker.NewWithClient(api.URL, http.DefaultClient)
+			go p.Run(ctx, c)
+
+			Eventually(status("/v1/users"), time.Second).Should(Equal(http.StatusNotFound))
+
+			engine.set(users)
+			engine.events <- "start"
+			Eventually(status("/v1/users"), time.Second).Should(Equal(http.StatusAccepted))
+
+			engine.set()
+			engine.events <- "die"
+			Eventually(status("/v1/users"), time.Second).Should(Equal(http.StatusNotFound))
+		})
+	})
+}
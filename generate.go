@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/gomicro/avenues/config"
+	"github.com/gomicro/avenues/openapi"
+	"gopkg.in/yaml.v2"
+)
+
+// generate writes a routes file with a static route for each prefix found in
+// the given OpenAPI specs, proxying to the spec's first server.
+func generate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	out := fs.String("o", "", "file to write the routes to, defaults to stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: avenues generate [-o routes.yaml] spec...")
+		fs.PrintDefaults()
+	}
+
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	routes := map[string]*config.Route{}
+	for _, p := range fs.Args() {
+		doc, err := openapi.Load(p)
+		if err != nil {
+			fatalf("Failed to load spec '%v': %v", p, err.Error())
+		}
+
+		backend, err := doc.Backend()
+		if err != nil {
+			fatalf("Failed to find backend for spec '%v': %v", p, err.Error())
+		}
+
+		for _, prefix := range doc.Prefixes() {
+			if existing, ok := routes[prefix]; ok && existing.Backend != backend {
+				fmt.Fprintf(os.Stderr, "Skipping '%v' from '%v': already routed to '%v'\n", prefix, p, existing.Backend)
+				continue
+			}
+
+			routes[prefix] = &config.Route{
+				Type:    "static",
+				Backend: backend,
+			}
+		}
+	}
+
+	writeRoutes(routes, *out)
+}
+
+// writeRoutes marshals the routes as a routes file to the given path, or to
+// stdout when no path is given.
+func writeRoutes(routes map[string]*config.Route, path string) {
+	b, err := yaml.Marshal(struct {
+		Routes map[string]*config.Route `yaml:"routes"`
+	}{routes})
+	if err != nil {
+		fatalf("Failed to marshal routes: %v", err.Error())
+	}
+
+	if path == "" {
+		_, err = os.Stdout.Write(b)
+	} else {
+		err = ioutil.WriteFile(path, b, 0644)
+	}
+
+	if err != nil {
+		fatalf("Failed to write routes: %v", err.Error())
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,363 @@
+// Package graphql reads just enough of a GraphQL request to tell which
+// operation it runs and which root fields that operation selects.
+package graphql
+
+import (
+	"fmt"
+)
+
+const (
+	// Query is the type of read operations
+	Query = "query"
+	// Mutation is the type of write operations
+	Mutation = "mutation"
+	// Subscription is the type of streaming operations
+	Subscription = "subscription"
+)
+
+// Operation represents the operation a GraphQL request runs
+type Operation struct {
+	Type   string
+	Name   string
+	Fields []string
+}
+
+// HasField reports whether the operation selects the given root field
+func (o *Operation) HasField(field string) bool {
+	for _, f := range o.Fields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+type selection struct {
+	fields  []string
+	spreads []string
+}
+
+type definition struct {
+	typ  string
+	name string
+	sel  *selection
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse reads a GraphQL document and returns the operation to run. The
+// operation name selects between multiple operations and may be empty when
+// the document only has one.
+func Parse(document, operationName string) (*Operation, error) {
+	tokens, err := lex(document)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	var ops []*definition
+	fragments := map[string]*selection{}
+
+	for p.peek().kind != eofToken {
+		def, err := p.definition()
+		if err != nil {
+			return nil, err
+		}
+
+		if def.typ == "fragment" {
+			fragments[def.name] = def.sel
+			continue
+		}
+
+		ops = append(ops, def)
+	}
+
+	var op *definition
+	switch {
+	case len(ops) == 0:
+		return nil, fmt.Errorf("document has no operations")
+	case operationName != "":
+		for _, o := range ops {
+			if o.name == operationName {
+				op = o
+				break
+			}
+		}
+
+		if op == nil {
+			return nil, fmt.Errorf("unknown operation: %v", operationName)
+		}
+	case len(ops) == 1:
+		op = ops[0]
+	default:
+		return nil, fmt.Errorf("operation name is required for documents with multiple operations")
+	}
+
+	return &Operation{
+		Type:   op.typ,
+		Name:   op.name,
+		Fields: resolveFields(op.sel, fragments, map[string]bool{}),
+	}, nil
+}
+
+// resolveFields returns the selection's fields along with those of any
+// fragments it spreads, without repeating a field.
+func resolveFields(sel *selection, fragments map[string]*selection, visited map[string]bool) []string {
+	var fields []string
+	seen := map[string]bool{}
+
+	add := func(fs []string) {
+		for _, f := range fs {
+			if !seen[f] {
+				seen[f] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	add(sel.fields)
+
+	for _, name := range sel.spreads {
+		frag, ok := fragments[name]
+		if !ok || visited[name] {
+			continue
+		}
+
+		visited[name] = true
+		add(resolveFields(frag, fragments, visited))
+	}
+
+	return fields
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != eofToken {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) is(value string) bool {
+	t := p.peek()
+	return t.kind != eofToken && t.kind != valueToken && t.value == value
+}
+
+func (p *parser) expect(value string) error {
+	if !p.is(value) {
+		return fmt.Errorf("expected %q, found %q", value, p.peek().value)
+	}
+
+	p.next()
+
+	return nil
+}
+
+func (p *parser) definition() (*definition, error) {
+	if p.is("{") {
+		sel, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+
+		return &definition{typ: Query, sel: sel}, nil
+	}
+
+	t := p.next()
+	if t.kind != nameToken {
+		return nil, fmt.Errorf("unexpected %q", t.value)
+	}
+
+	switch t.value {
+	case Query, Mutation, Subscription:
+		def := &definition{typ: t.value}
+
+		if p.peek().kind == nameToken {
+			def.name = p.next().value
+		}
+
+		if p.is("(") {
+			err := p.skipBalanced("(", ")")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := p.skipDirectives()
+		if err != nil {
+			return nil, err
+		}
+
+		def.sel, err = p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+
+		return def, nil
+	case "fragment":
+		name := p.next()
+		if name.kind != nameToken {
+			return nil, fmt.Errorf("expected fragment name, found %q", name.value)
+		}
+
+		err := p.expect("on")
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+
+		err = p.skipDirectives()
+		if err != nil {
+			return nil, err
+		}
+
+		sel, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+
+		return &definition{typ: "fragment", name: name.value, sel: sel}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported definition: %v", t.value)
+}
+
+// selectionSet reads a selection set, keeping the names of its fields and
+// skipping over everything nested below them.
+func (p *parser) selectionSet() (*selection, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	sel := &selection{}
+
+	for !p.is("}") {
+		if p.peek().kind == eofToken {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+
+		if p.is("...") {
+			p.next()
+
+			if p.peek().kind == nameToken && p.peek().value != "on" {
+				sel.spreads = append(sel.spreads, p.next().value)
+
+				err := p.skipDirectives()
+				if err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if p.is("on") {
+				p.next()
+				p.next()
+			}
+
+			err := p.skipDirectives()
+			if err != nil {
+				return nil, err
+			}
+
+			inline, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+
+			sel.fields = append(sel.fields, inline.fields...)
+			sel.spreads = append(sel.spreads, inline.spreads...)
+
+			continue
+		}
+
+		name := p.next()
+		if name.kind != nameToken {
+			return nil, fmt.Errorf("expected field, found %q", name.value)
+		}
+
+		field := name.value
+		if p.is(":") {
+			p.next()
+
+			actual := p.next()
+			if actual.kind != nameToken {
+				return nil, fmt.Errorf("expected field after alias, found %q", actual.value)
+			}
+
+			field = actual.value
+		}
+
+		sel.fields = append(sel.fields, field)
+
+		if p.is("(") {
+			err := p.skipBalanced("(", ")")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := p.skipDirectives()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.is("{") {
+			err := p.skipBalanced("{", "}")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.next()
+
+	return sel, nil
+}
+
+func (p *parser) skipDirectives() error {
+	for p.is("@") {
+		p.next()
+		p.next()
+
+		if p.is("(") {
+			err := p.skipBalanced("(", ")")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) skipBalanced(open, close string) error {
+	depth := 0
+
+	for {
+		t := p.next()
+
+		switch {
+		case t.kind == eofToken:
+			return fmt.Errorf("expected %q before end of document", close)
+		case t.kind == punctToken && t.value == open:
+			depth++
+		case t.kind == punctToken && t.value == close:
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package graphql_test
+
+import (
+	"testing"
+
+	"github.com/gomicro/avenues/graphql"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestGraphQL(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Parsing operations", func() {
+		g.It("should read an anonymous query shorthand", func() {
+			op, err := graphql.Parse(`{ viewer { id } repository(name: "avenues") { stars } }`, "")
+			Expect(err).To(BeNil())
+			Expect(op.Type).To(Equal(graphql.Query))
+			Expect(op.Name).To(Equal(""))
+			Expect(op.Fields).To(Equal([]string{"viewer", "repository"}))
+		})
+
+		g.It("should read named operations with variables and directives", func() {
+			op, err := graphql.Parse(`
+# create a user
+mutation CreateUser($input: UserInput! = {name: "a, b"}) @audit(reason: """multi
+line""") {
+  created: createUser(input: $input) @include(if: true) {
+    id
+  }
+}`, "")
+			Expect(err).To(BeNil())
+			Expect(op.Type).To(Equal(graphql.Mutation))
+			Expect(op.Name).To(Equal("CreateUser"))
+			Expect(op.Fields).To(Equal([]string{"createUser"}))
+			Expect(op.HasField("createUser")).To(BeTrue())
+			Expect(op.HasField("created")).To(BeFalse())
+		})
+
+		g.It("should select the named operation from many", func() {
+			doc := `
+query GetUser { user(id: 1) { name } }
+subscription OnMessage { messageAdded { body } }
+`
+			op, err := graphql.Parse(doc, "OnMessage")
+			Expect(err).To(BeNil())
+			Expect(op.Type).To(Equal(graphql.Subscription))
+			Expect(op.Fields).To(Equal([]string{"messageAdded"}))
+
+			_, err = graphql.Parse(doc, "")
+			Expect(err).NotTo(BeNil())
+
+			_, err = graphql.Parse(doc, "Missing")
+			Expect(err).NotTo(BeNil())
+		})
+
+		g.It("should include fields from fragments", func() {
+			op, err := graphql.Parse(`
+query Dashboard {
+  ...Counts
+  ... on Query { alerts { id } }
+  ... @skip(if: false) { me { id } }
+}
+fragment Counts on Query { users { total } ...More }
+fragment More on Query { orders { total } ...Counts }
+`, "")
+			Expect(err).To(BeNil())
+			Expect(op.Fields).To(Equal([]string{"alerts", "me", "users", "orders"}))
+		})
+
+		g.It("should reject malformed documents", func() {
+			_, err := graphql.Parse(`query { user { id }`, "")
+			Expect(err).NotTo(BeNil())
+
+			_, err = graphql.Parse(`type User { id: ID }`, "")
+			Expect(err).NotTo(BeNil())
+
+			_, err = graphql.Parse(`{ user(name: "unterminated) }`, "")
+			Expect(err).NotTo(BeNil())
+
+			_, err = graphql.Parse(``, "")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
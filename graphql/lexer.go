@@ -0,0 +1,104 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	punctToken
+	nameToken
+	valueToken
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// lex splits a GraphQL document into tokens, dropping whitespace, commas, and
+// comments, which carry no meaning in the language.
+func lex(src string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case strings.HasPrefix(src[i:], "\ufeff"):
+			i += len("\ufeff")
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{kind: punctToken, value: "..."})
+			i += 3
+		case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+			tokens = append(tokens, token{kind: punctToken, value: string(c)})
+			i++
+		case isNameStart(c):
+			start := i
+			for i < len(src) && isNameContinue(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: nameToken, value: src[start:i]})
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(src) && (isNameContinue(src[i]) || src[i] == '.' || src[i] == '+' || src[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, token{kind: valueToken, value: src[start:i]})
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			for end >= 0 && strings.HasSuffix(src[i+3:i+3+end], `\`) {
+				next := strings.Index(src[i+3+end+3:], `"""`)
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += 3 + next
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string")
+			}
+			tokens = append(tokens, token{kind: valueToken, value: src[i : i+3+end+3]})
+			i += 3 + end + 3
+		case c == '"':
+			start := i
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\\' {
+					i++
+				}
+				if i < len(src) && (src[i] == '\n' || src[i] == '\r') {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{kind: valueToken, value: src[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+
+	return append(tokens, token{kind: eofToken}), nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,75 @@
+// Package labels interprets the avenues labels placed on compose services and
+// containers to describe how they should be routed to.
+package labels
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gomicro/avenues/config"
+)
+
+const (
+	// Route is a comma separated list of route prefixes to send to the service
+	Route = "avenues.route"
+	// Port is the port within the container to send requests to
+	Port = "avenues.port"
+	// Type is the route type to use, defaulting to static
+	Type = "avenues.type"
+	// Scheme is the scheme used to reach the service, defaulting to http
+	Scheme = "avenues.scheme"
+
+	defaultScheme = "http"
+	defaultType   = "static"
+)
+
+// Routed reports whether the labels ask for the service to be routed to
+func Routed(l map[string]string) bool {
+	_, ok := l[Route]
+	return ok
+}
+
+// PortOf returns the port label, if one is set.
+func PortOf(l map[string]string) (int, bool, error) {
+	p, ok := l[Port]
+	if !ok {
+		return 0, false, nil
+	}
+
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid %v label: %v", Port, p)
+	}
+
+	return port, true, nil
+}
+
+// Routes builds a route for every prefix in the route label, with backends
+// addressing the given host and port.
+func Routes(l map[string]string, host string, port int) map[string]*config.Route {
+	scheme := l[Scheme]
+	if scheme == "" {
+		scheme = defaultScheme
+	}
+
+	typ := l[Type]
+	if typ == "" {
+		typ = defaultType
+	}
+
+	routes := map[string]*config.Route{}
+	for _, prefix := range strings.Split(l[Route], ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+
+		routes[prefix] = &config.Route{
+			Type:    typ,
+			Backend: fmt.Sprintf("%v://%v:%v", scheme, host, port),
+		}
+	}
+
+	return routes
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"net/http"
 	"os"
 
 	"github.com/gomicro/avenues/config"
+	"github.com/gomicro/avenues/docker"
 	log "github.com/gomicro/ledger"
 )
 
@@ -16,10 +18,7 @@ var (
 )
 
 func configure() {
-	if version == "" {
-		version = "dev-local"
-	}
-	log.Infof("Avenues %v", version)
+	logVersion()
 
 	c, err := config.ParseFromFile()
 	if err != nil {
@@ -33,12 +32,46 @@ func configure() {
 	log.Debug("Configuration complete")
 }
 
+func logVersion() {
+	if version == "" {
+		version = "dev-local"
+	}
+	log.Infof("Avenues %v", version)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "generate":
+			generate(os.Args[2:])
+			return
+		case "compose":
+			composeRoutes(os.Args[2:])
+			return
+		case "top":
+			topTraffic(os.Args[2:])
+			return
+		}
+	}
+
 	configure()
+	discover()
+	serve()
+}
 
-	log.Infof("Listening on %v:%v", "0.0.0.0", "4567")
+func discover() {
+	if conf.Discovery == nil {
+		return
+	}
 
-	http.Handle("/", conf)
+	if conf.Discovery.Docker != "" {
+		log.Infof("Discovering routes from docker at %v", conf.Discovery.Docker)
+		go docker.New(conf.Discovery.Docker).Run(context.Background(), conf)
+	}
+}
+
+func serve() {
+	var cfg *tls.Config
 
 	if conf.Key != "" && conf.Cert != "" {
 		log.Info("Serving with SSL")
@@ -49,7 +82,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		cfg := &tls.Config{
+		cfg = &tls.Config{
 			MinVersion:               tls.VersionTLS12,
 			PreferServerCipherSuites: true,
 			CipherSuites: []uint16{
@@ -65,23 +98,35 @@ func main() {
 			},
 			Certificates: []tls.Certificate{cert},
 		}
+	} else {
+		log.Info("Serving without SSL")
+	}
 
-		srv := &http.Server{
-			Addr:      net.JoinHostPort("0.0.0.0", "4567"),
-			TLSConfig: cfg,
-		}
+	log.Infof("Serving admin on %v", conf.Admin.Address)
+	go listen(conf.Admin.Address, conf.AdminHandler(), cfg)
+
+	log.Infof("Listening on %v:%v", "0.0.0.0", "4567")
+	listen(net.JoinHostPort("0.0.0.0", "4567"), conf, cfg)
+}
+
+// listen serves the handler on the address, with TLS when a config is given,
+// exiting if the server stops
+func listen(addr string, h http.Handler, cfg *tls.Config) {
+	srv := &http.Server{
+		Addr:      addr,
+		Handler:   h,
+		TLSConfig: cfg,
+	}
 
+	var err error
+	if cfg != nil {
 		err = srv.ListenAndServeTLS("", "")
-		if err != nil {
-			log.Fatalf("something went horribly wrong: %v", err.Error())
-			os.Exit(1)
-		}
 	} else {
-		log.Info("Serving without SSL")
-		err := http.ListenAndServe("0.0.0.0:4567", nil)
-		if err != nil {
-			log.Fatalf("something went horribly wrong: %v", err.Error())
-			os.Exit(1)
-		}
+		err = srv.ListenAndServe()
+	}
+
+	if err != nil {
+		log.Fatalf("something went horribly wrong: %v", err.Error())
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,272 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxExampleDepth bounds how deep example values are generated from nested
+// schemas, guarding against recursive schemas.
+const maxExampleDepth = 8
+
+// MockResponse represents a response synthesised from an operation
+type MockResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// ParsePrefer reads the preferences of a Prefer header, such as
+// "code=404, example=missing", into a map.
+func ParsePrefer(header string) map[string]string {
+	prefs := map[string]string{}
+
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		prefs[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+	}
+
+	return prefs
+}
+
+// Mock synthesises a response for the operation. The response code is taken
+// from the code preference when present, otherwise the lowest success code is
+// used. The body comes from the named example preference, the media type's
+// examples, or is generated from the schema.
+func (d *Document) Mock(op *Operation, prefs map[string]string) (*MockResponse, error) {
+	code, resp, err := d.selectResponse(op, prefs["code"])
+	if err != nil {
+		return nil, err
+	}
+
+	status := statusFromCode(code)
+	mock := &MockResponse{Status: status}
+
+	if resp == nil || len(resp.Content) == 0 {
+		return mock, nil
+	}
+
+	contentType, media := selectMediaType(resp.Content)
+	mock.ContentType = contentType
+
+	value, ok := d.exampleFor(media, prefs["example"])
+	if !ok {
+		return mock, nil
+	}
+
+	if s, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		mock.Body = []byte(s)
+		return mock, nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal example: %v", err.Error())
+	}
+
+	mock.Body = b
+
+	return mock, nil
+}
+
+func (d *Document) selectResponse(op *Operation, preferred string) (string, *Response, error) {
+	if len(op.Responses) == 0 {
+		return "200", nil, nil
+	}
+
+	if preferred != "" {
+		if r, ok := op.Responses[preferred]; ok {
+			return preferred, d.Response(r), nil
+		}
+
+		if len(preferred) == 3 {
+			wildcard := fmt.Sprintf("%vXX", preferred[:1])
+			if r, ok := op.Responses[wildcard]; ok {
+				return preferred, d.Response(r), nil
+			}
+		}
+
+		if r, ok := op.Responses["default"]; ok {
+			return preferred, d.Response(r), nil
+		}
+
+		return "", nil, fmt.Errorf("no response defined for code: %v", preferred)
+	}
+
+	codes := make([]string, 0, len(op.Responses))
+	for c := range op.Responses {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+
+	for _, c := range codes {
+		if strings.HasPrefix(c, "2") {
+			return c, d.Response(op.Responses[c]), nil
+		}
+	}
+
+	if r, ok := op.Responses["default"]; ok {
+		return "200", d.Response(r), nil
+	}
+
+	return codes[0], d.Response(op.Responses[codes[0]]), nil
+}
+
+func statusFromCode(code string) int {
+	code = strings.Replace(strings.ToUpper(code), "XX", "00", 1)
+
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return http.StatusOK
+	}
+
+	return status
+}
+
+func selectMediaType(content map[string]*MediaType) (string, *MediaType) {
+	if m, ok := content["application/json"]; ok {
+		return "application/json", m
+	}
+
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	return types[0], content[types[0]]
+}
+
+func (d *Document) exampleFor(media *MediaType, name string) (interface{}, bool) {
+	if media == nil {
+		return nil, false
+	}
+
+	if name != "" {
+		if e := d.Example(media.Examples[name]); e != nil {
+			return normalize(e.Value), true
+		}
+	}
+
+	if media.Example != nil {
+		return normalize(media.Example), true
+	}
+
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for n := range media.Examples {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		if e := d.Example(media.Examples[names[0]]); e != nil {
+			return normalize(e.Value), true
+		}
+	}
+
+	if media.Schema != nil {
+		return d.ExampleValue(media.Schema), true
+	}
+
+	return nil, false
+}
+
+// ExampleValue generates a value conforming to the schema, preferring any
+// examples, defaults, or enumerations the schema declares.
+func (d *Document) ExampleValue(s *Schema) interface{} {
+	return d.exampleValue(s, 0)
+}
+
+func (d *Document) exampleValue(s *Schema, depth int) interface{} {
+	s = d.Schema(s)
+	if s == nil || depth > maxExampleDepth {
+		return nil
+	}
+
+	switch {
+	case s.Example != nil:
+		return normalize(s.Example)
+	case s.Default != nil:
+		return normalize(s.Default)
+	case len(s.Enum) > 0:
+		return normalize(s.Enum[0])
+	case len(s.AllOf) > 0:
+		merged := map[string]interface{}{}
+		for _, sub := range s.AllOf {
+			if m, ok := d.exampleValue(sub, depth+1).(map[string]interface{}); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+
+		return merged
+	case len(s.OneOf) > 0:
+		return d.exampleValue(s.OneOf[0], depth+1)
+	case len(s.AnyOf) > 0:
+		return d.exampleValue(s.AnyOf[0], depth+1)
+	}
+
+	switch s.Type {
+	case "object", "":
+		if s.Type == "" && len(s.Properties) == 0 {
+			return nil
+		}
+
+		obj := map[string]interface{}{}
+		for name, prop := range s.Properties {
+			obj[name] = d.exampleValue(prop, depth+1)
+		}
+
+		return obj
+	case "array":
+		if s.Items == nil {
+			return []interface{}{}
+		}
+
+		return []interface{}{d.exampleValue(s.Items, depth+1)}
+	case "string":
+		return exampleString(s.Format)
+	case "integer":
+		if s.Minimum != nil {
+			return int(*s.Minimum)
+		}
+
+		return 0
+	case "number":
+		if s.Minimum != nil {
+			return *s.Minimum
+		}
+
+		return 0.0
+	case "boolean":
+		return true
+	}
+
+	return nil
+}
+
+func exampleString(format string) string {
+	switch format {
+	case "date-time":
+		return "2021-01-01T00:00:00Z"
+	case "date":
+		return "2021-01-01"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	}
+
+	return "string"
+}
@@ -0,0 +1,279 @@
+// Package openapi provides just enough of an OpenAPI 3 document model for
+// Avenues to mock, validate, and route against specs.
+package openapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Document represents an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Servers    []*Server            `yaml:"servers"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components *Components          `yaml:"components"`
+}
+
+// Server represents a server the API is served from
+type Server struct {
+	URL string `yaml:"url"`
+}
+
+// PathItem represents the operations available on a single path
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Options    *Operation   `yaml:"options"`
+	Head       *Operation   `yaml:"head"`
+	Patch      *Operation   `yaml:"patch"`
+	Trace      *Operation   `yaml:"trace"`
+}
+
+// Operation represents a single API operation on a path
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+// Parameter represents a single operation parameter
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+}
+
+// RequestBody represents the body accepted by an operation
+type RequestBody struct {
+	Ref      string                `yaml:"$ref"`
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+// Response represents a single response from an operation
+type Response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+// MediaType represents the schema and examples for a content type
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  interface{}         `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+// Example represents a named example value
+type Example struct {
+	Ref     string      `yaml:"$ref"`
+	Summary string      `yaml:"summary"`
+	Value   interface{} `yaml:"value"`
+}
+
+// Schema represents the subset of JSON schema used by OpenAPI that Avenues
+// understands
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	AdditionalProperties interface{}        `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	Required             []string           `yaml:"required"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Example              interface{}        `yaml:"example"`
+	Default              interface{}        `yaml:"default"`
+	Nullable             bool               `yaml:"nullable"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+	Pattern              string             `yaml:"pattern"`
+}
+
+// Components represents the reusable objects referenced throughout a document
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas"`
+	Responses     map[string]*Response    `yaml:"responses"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	Examples      map[string]*Example     `yaml:"examples"`
+}
+
+// Load reads an OpenAPI document in either YAML or JSON from the given file
+func Load(path string) (*Document, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %v", err.Error())
+	}
+
+	return Parse(b)
+}
+
+// Parse reads an OpenAPI document in either YAML or JSON from the provided
+// bytes
+func Parse(b []byte) (*Document, error) {
+	var doc Document
+	err := yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec: %v", err.Error())
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version: %v", doc.OpenAPI)
+	}
+
+	if doc.Components == nil {
+		doc.Components = &Components{}
+	}
+
+	return &doc, nil
+}
+
+// Operations returns the operations of the path keyed by upper case method
+func (p *PathItem) Operations() map[string]*Operation {
+	ops := map[string]*Operation{}
+
+	for method, op := range map[string]*Operation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+		http.MethodTrace:   p.Trace,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+
+	return ops
+}
+
+// Match represents an operation found for a request
+type Match struct {
+	Path       string
+	PathItem   *PathItem
+	Operation  *Operation
+	Parameters map[string]string
+}
+
+// Find returns the operation for the given method and request path. Paths are
+// matched both as given and with any server base path removed. Templates with
+// more literal segments are preferred, so /users/me wins over /users/{id}.
+func (d *Document) Find(method, path string) (*Match, bool) {
+	candidates := []string{path}
+	for _, base := range d.BasePaths() {
+		if base != "" && strings.HasPrefix(path, base) {
+			candidates = append(candidates, strings.TrimPrefix(path, base))
+		}
+	}
+
+	templates := make([]string, 0, len(d.Paths))
+	for t := range d.Paths {
+		templates = append(templates, t)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		li, lj := literalSegments(templates[i]), literalSegments(templates[j])
+		if li != lj {
+			return li > lj
+		}
+
+		return templates[i] < templates[j]
+	})
+
+	for _, c := range candidates {
+		for _, t := range templates {
+			params, ok := matchTemplate(t, c)
+			if !ok {
+				continue
+			}
+
+			item := d.Paths[t]
+			op, ok := item.Operations()[strings.ToUpper(method)]
+			if !ok {
+				continue
+			}
+
+			return &Match{
+				Path:       t,
+				PathItem:   item,
+				Operation:  op,
+				Parameters: params,
+			}, true
+		}
+	}
+
+	return nil, false
+}
+
+// BasePaths returns the path portion of each of the document's servers
+func (d *Document) BasePaths() []string {
+	var paths []string
+	for _, s := range d.Servers {
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			continue
+		}
+
+		paths = append(paths, strings.TrimSuffix(u.Path, "/"))
+	}
+
+	return paths
+}
+
+func matchTemplate(template, path string) (map[string]string, bool) {
+	ts := strings.Split(strings.Trim(template, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(ts) != len(ps) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, t := range ts {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			params[strings.Trim(t, "{}")] = ps[i]
+			continue
+		}
+
+		if t != ps[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func literalSegments(template string) int {
+	n := 0
+	for _, s := range strings.Split(strings.Trim(template, "/"), "/") {
+		if !strings.HasPrefix(s, "{") {
+			n++
+		}
+	}
+
+	return n
+}
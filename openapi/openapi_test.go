@@ -0,0 +1,149 @@
+package openapi_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gomicro/avenues/openapi"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+const spec = `
+openapi: 3.0.0
+servers:
+  - url: https://api.example.com/v2
+paths:
+  /users/{id}:
+    get:
+      responses:
+        "2XX":
+          description: A user
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+        default:
+          description: An error
+          content:
+            text/plain:
+              example: something went wrong
+  /users/me:
+    get:
+      responses:
+        "200":
+          description: The current user
+components:
+  schemas:
+    User:
+      allOf:
+        - $ref: "#/components/schemas/Base"
+        - type: object
+          properties:
+            email:
+              type: string
+              format: email
+            roles:
+              type: array
+              items:
+                type: string
+                enum: [admin, member]
+            active:
+              type: boolean
+    Base:
+      type: object
+      properties:
+        id:
+          type: string
+          format: uuid
+`
+
+func TestOpenAPI(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("OpenAPI", func() {
+		var doc *openapi.Document
+
+		g.BeforeEach(func() {
+			d, err := openapi.Parse([]byte(spec))
+			Expect(err).To(BeNil())
+			doc = d
+		})
+
+		g.It("should reject documents that are not OpenAPI 3", func() {
+			_, err := openapi.Parse([]byte(`swagger: "2.0"`))
+			Expect(err).NotTo(BeNil())
+		})
+
+		g.Describe("Finding Operations", func() {
+			g.It("should match templated paths", func() {
+				m, ok := doc.Find(http.MethodGet, "/users/42")
+				Expect(ok).To(BeTrue())
+				Expect(m.Path).To(Equal("/users/{id}"))
+				Expect(m.Parameters["id"]).To(Equal("42"))
+			})
+
+			g.It("should prefer literal paths", func() {
+				m, ok := doc.Find(http.MethodGet, "/users/me")
+				Expect(ok).To(BeTrue())
+				Expect(m.Path).To(Equal("/users/me"))
+			})
+
+			g.It("should strip the server base path", func() {
+				m, ok := doc.Find(http.MethodGet, "/v2/users/42")
+				Expect(ok).To(BeTrue())
+				Expect(m.Path).To(Equal("/users/{id}"))
+			})
+
+			g.It("should not match unknown methods", func() {
+				_, ok := doc.Find(http.MethodDelete, "/users/42")
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		g.Describe("Mocking", func() {
+			g.It("should generate a body from the schema", func() {
+				m, _ := doc.Find(http.MethodGet, "/users/42")
+
+				mock, err := doc.Mock(m.Operation, nil)
+				Expect(err).To(BeNil())
+				Expect(mock.Status).To(Equal(http.StatusOK))
+				Expect(mock.ContentType).To(Equal("application/json"))
+				Expect(mock.Body).To(MatchJSON(`{
+					"id": "00000000-0000-0000-0000-000000000000",
+					"email": "user@example.com",
+					"roles": ["admin"],
+					"active": true
+				}`))
+			})
+
+			g.It("should fall back to the default response", func() {
+				m, _ := doc.Find(http.MethodGet, "/users/42")
+
+				mock, err := doc.Mock(m.Operation, openapi.ParsePrefer("code=500"))
+				Expect(err).To(BeNil())
+				Expect(mock.Status).To(Equal(http.StatusInternalServerError))
+				Expect(mock.ContentType).To(Equal("text/plain"))
+				Expect(string(mock.Body)).To(Equal("something went wrong"))
+			})
+
+			g.It("should answer without a body when there is no content", func() {
+				m, _ := doc.Find(http.MethodGet, "/users/me")
+
+				mock, err := doc.Mock(m.Operation, nil)
+				Expect(err).To(BeNil())
+				Expect(mock.Status).To(Equal(http.StatusOK))
+				Expect(mock.Body).To(BeEmpty())
+			})
+		})
+
+		g.Describe("Preferences", func() {
+			g.It("should parse a prefer header", func() {
+				prefs := openapi.ParsePrefer(`code=404, example="missing"; respond-async`)
+				Expect(prefs).To(Equal(map[string]string{"code": "404", "example": "missing"}))
+			})
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRefDepth bounds how many references are followed to resolve a single
+// object, guarding against reference cycles.
+const maxRefDepth = 16
+
+// Schema returns the schema with any reference resolved against the document's
+// components.
+func (d *Document) Schema(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < maxRefDepth; i++ {
+		s = d.Components.Schemas[refName(s.Ref)]
+	}
+
+	return s
+}
+
+// Parameter returns the parameter with any reference resolved against the
+// document's components.
+func (d *Document) Parameter(p *Parameter) *Parameter {
+	for i := 0; p != nil && p.Ref != "" && i < maxRefDepth; i++ {
+		p = d.Components.Parameters[refName(p.Ref)]
+	}
+
+	return p
+}
+
+// RequestBody returns the request body with any reference resolved against
+// the document's components.
+func (d *Document) RequestBody(b *RequestBody) *RequestBody {
+	for i := 0; b != nil && b.Ref != "" && i < maxRefDepth; i++ {
+		b = d.Components.RequestBodies[refName(b.Ref)]
+	}
+
+	return b
+}
+
+// Response returns the response with any reference resolved against the
+// document's components.
+func (d *Document) Response(r *Response) *Response {
+	for i := 0; r != nil && r.Ref != "" && i < maxRefDepth; i++ {
+		r = d.Components.Responses[refName(r.Ref)]
+	}
+
+	return r
+}
+
+// Example returns the example with any reference resolved against the
+// document's components.
+func (d *Document) Example(e *Example) *Example {
+	for i := 0; e != nil && e.Ref != "" && i < maxRefDepth; i++ {
+		e = d.Components.Examples[refName(e.Ref)]
+	}
+
+	return e
+}
+
+// refName returns the component name of a local reference such as
+// #/components/schemas/Pet
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// normalize converts the maps produced by the yaml decoder into maps with
+// string keys so values can be encoded as JSON.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = normalize(v)
+		}
+
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, v := range t {
+			s[i] = normalize(v)
+		}
+
+		return s
+	default:
+		return v
+	}
+}
@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Backend returns the scheme and host of the document's first server, which
+// is where requests for its paths should be proxied.
+func (d *Document) Backend() (string, error) {
+	if len(d.Servers) == 0 {
+		return "", fmt.Errorf("spec has no servers")
+	}
+
+	u, err := url.Parse(d.Servers[0].URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse server url: %v", err.Error())
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("server url is not absolute: %v", d.Servers[0].URL)
+	}
+
+	return fmt.Sprintf("%v://%v", u.Scheme, u.Host), nil
+}
+
+// Prefixes returns the route prefixes covering every path in the document.
+// Each prefix is the server base path joined with the literal segments that
+// lead the path, and prefixes already covered by a shorter one are dropped.
+func (d *Document) Prefixes() []string {
+	base := ""
+	if paths := d.BasePaths(); len(paths) > 0 {
+		base = paths[0]
+	}
+
+	seen := map[string]bool{}
+	for p := range d.Paths {
+		var literal []string
+		for _, s := range strings.Split(strings.Trim(p, "/"), "/") {
+			if s == "" || strings.HasPrefix(s, "{") {
+				break
+			}
+
+			literal = append(literal, s)
+		}
+
+		seen[path.Join("/", base, strings.Join(literal, "/"))] = true
+	}
+
+	prefixes := make([]string, 0, len(seen))
+	for p := range seen {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+
+	var covering []string
+	for _, p := range prefixes {
+		covered := false
+		for _, c := range covering {
+			if c == "/" || strings.HasPrefix(p, c+"/") {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			covering = append(covering, p)
+		}
+	}
+
+	return covering
+}
@@ -0,0 +1,72 @@
+package openapi_test
+
+import (
+	"testing"
+
+	"github.com/gomicro/avenues/openapi"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestRoutes(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Routes", func() {
+		g.It("should derive prefixes and a backend from the spec", func() {
+			doc, err := openapi.Parse([]byte(`
+openapi: 3.0.0
+servers:
+  - url: http://users:4567/v1/
+paths:
+  /users:
+    get: {}
+  /users/{id}:
+    get: {}
+  /users/{id}/teams:
+    get: {}
+  /teams/{id}:
+    get: {}
+  /health:
+    get: {}
+`))
+			Expect(err).To(BeNil())
+
+			backend, err := doc.Backend()
+			Expect(err).To(BeNil())
+			Expect(backend).To(Equal("http://users:4567"))
+
+			Expect(doc.Prefixes()).To(Equal([]string{"/v1/health", "/v1/teams", "/v1/users"}))
+		})
+
+		g.It("should collapse to the base path for templated roots", func() {
+			doc, err := openapi.Parse([]byte(`
+openapi: 3.0.0
+servers:
+  - url: http://orders:4567/api
+paths:
+  /{tenant}/orders:
+    get: {}
+  /status:
+    get: {}
+`))
+			Expect(err).To(BeNil())
+
+			Expect(doc.Prefixes()).To(Equal([]string{"/api"}))
+		})
+
+		g.It("should require an absolute server", func() {
+			doc, err := openapi.Parse([]byte(`
+openapi: 3.0.0
+servers:
+  - url: /v1
+paths: {}
+`))
+			Expect(err).To(BeNil())
+
+			_, err = doc.Backend()
+			Expect(err).NotTo(BeNil())
+		})
+	})
+}
@@ -0,0 +1,361 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidateRequest checks the request and its body against the spec, returning
+// a description of every violation found.
+func (d *Document) ValidateRequest(req *http.Request, body []byte) []string {
+	m, ok := d.Find(req.Method, req.URL.Path)
+	if !ok {
+		return []string{fmt.Sprintf("no operation for %v %v", req.Method, req.URL.Path)}
+	}
+
+	var violations []string
+
+	for _, p := range d.parameters(m) {
+		var raw string
+		var present bool
+
+		switch p.In {
+		case "path":
+			raw, present = m.Parameters[p.Name]
+		case "query":
+			vs, ok := req.URL.Query()[p.Name]
+			if ok && len(vs) > 0 {
+				raw, present = vs[0], true
+			}
+		case "header":
+			raw = req.Header.Get(p.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		if !present {
+			if p.Required {
+				violations = append(violations, fmt.Sprintf("%v parameter %v is required", p.In, p.Name))
+			}
+
+			continue
+		}
+
+		violations = append(violations, d.validateParameter(p, raw)...)
+	}
+
+	rb := d.RequestBody(m.Operation.RequestBody)
+	if rb == nil {
+		return violations
+	}
+
+	if len(body) == 0 {
+		if rb.Required {
+			violations = append(violations, "request body is required")
+		}
+
+		return violations
+	}
+
+	return append(violations, d.validateContent("request body", rb.Content, req.Header.Get("Content-Type"), body)...)
+}
+
+// ValidateResponse checks a response to the request against the spec,
+// returning a description of every violation found.
+func (d *Document) ValidateResponse(req *http.Request, status int, header http.Header, body []byte) []string {
+	m, ok := d.Find(req.Method, req.URL.Path)
+	if !ok {
+		return []string{fmt.Sprintf("no operation for %v %v", req.Method, req.URL.Path)}
+	}
+
+	code := strconv.Itoa(status)
+
+	r, ok := m.Operation.Responses[code]
+	if !ok {
+		r, ok = m.Operation.Responses[fmt.Sprintf("%vXX", code[:1])]
+	}
+
+	if !ok {
+		r, ok = m.Operation.Responses["default"]
+	}
+
+	if !ok {
+		return []string{fmt.Sprintf("response status %v is not defined", status)}
+	}
+
+	r = d.Response(r)
+	if r == nil || len(r.Content) == 0 || len(body) == 0 {
+		return nil
+	}
+
+	return d.validateContent("response body", r.Content, header.Get("Content-Type"), body)
+}
+
+func (d *Document) parameters(m *Match) []*Parameter {
+	params := map[string]*Parameter{}
+
+	for _, list := range [][]*Parameter{m.PathItem.Parameters, m.Operation.Parameters} {
+		for _, p := range list {
+			p = d.Parameter(p)
+			if p != nil {
+				params[fmt.Sprintf("%v:%v", p.In, p.Name)] = p
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := make([]*Parameter, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, params[k])
+	}
+
+	return list
+}
+
+func (d *Document) validateParameter(p *Parameter, raw string) []string {
+	s := d.Schema(p.Schema)
+	if s == nil {
+		return nil
+	}
+
+	var v interface{} = raw
+
+	switch s.Type {
+	case "integer", "number":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("%v parameter %v must be of type %v", p.In, p.Name, s.Type)}
+		}
+
+		v = f
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return []string{fmt.Sprintf("%v parameter %v must be a boolean", p.In, p.Name)}
+		}
+
+		v = b
+	case "array", "object":
+		return nil
+	}
+
+	return d.ValidateValue(s, v, fmt.Sprintf("%v parameter %v", p.In, p.Name))
+}
+
+func (d *Document) validateContent(name string, content map[string]*MediaType, contentType string, body []byte) []string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = contentType
+	}
+
+	media, ok := content[mt]
+	if !ok {
+		media, ok = content[fmt.Sprintf("%v/*", strings.Split(mt, "/")[0])]
+	}
+
+	if !ok {
+		media, ok = content["*/*"]
+	}
+
+	if !ok {
+		return []string{fmt.Sprintf("%v content type %q is not defined", name, contentType)}
+	}
+
+	if media == nil || media.Schema == nil || !strings.Contains(mt, "json") {
+		return nil
+	}
+
+	var v interface{}
+	err = json.Unmarshal(body, &v)
+	if err != nil {
+		return []string{fmt.Sprintf("%v is not valid json: %v", name, err.Error())}
+	}
+
+	return d.ValidateValue(media.Schema, v, name)
+}
+
+// ValidateValue checks a decoded JSON value against the schema, returning a
+// description of every violation found. The path names the value in the
+// descriptions.
+func (d *Document) ValidateValue(s *Schema, v interface{}, path string) []string {
+	return d.validateValue(s, v, path, 0)
+}
+
+func (d *Document) validateValue(s *Schema, v interface{}, path string, depth int) []string {
+	s = d.Schema(s)
+	if s == nil || depth > maxRefDepth*maxExampleDepth {
+		return nil
+	}
+
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+
+		return []string{fmt.Sprintf("%v must not be null", path)}
+	}
+
+	var violations []string
+
+	for _, sub := range s.AllOf {
+		violations = append(violations, d.validateValue(sub, v, path, depth+1)...)
+	}
+
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, sub := range s.OneOf {
+			if len(d.validateValue(sub, v, path, depth+1)) == 0 {
+				matched++
+			}
+		}
+
+		if matched != 1 {
+			violations = append(violations, fmt.Sprintf("%v must match exactly one schema, matched %v", path, matched))
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if len(d.validateValue(sub, v, path, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			violations = append(violations, fmt.Sprintf("%v must match at least one schema", path))
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		violations = append(violations, fmt.Sprintf("%v must be one of %v", path, normalize(s.Enum)))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%v must be an object", path))
+		}
+
+		violations = append(violations, d.validateObject(s, obj, path, depth)...)
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%v must be an array", path))
+		}
+
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			violations = append(violations, fmt.Sprintf("%v must have at least %v items", path, *s.MinItems))
+		}
+
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			violations = append(violations, fmt.Sprintf("%v must have at most %v items", path, *s.MaxItems))
+		}
+
+		for i, item := range arr {
+			violations = append(violations, d.validateValue(s.Items, item, fmt.Sprintf("%v[%v]", path, i), depth+1)...)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return append(violations, fmt.Sprintf("%v must be a string", path))
+		}
+
+		if s.MinLength != nil && len(str) < *s.MinLength {
+			violations = append(violations, fmt.Sprintf("%v must be at least %v characters", path, *s.MinLength))
+		}
+
+		if s.MaxLength != nil && len(str) > *s.MaxLength {
+			violations = append(violations, fmt.Sprintf("%v must be at most %v characters", path, *s.MaxLength))
+		}
+
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err == nil && !re.MatchString(str) {
+				violations = append(violations, fmt.Sprintf("%v must match pattern %v", path, s.Pattern))
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return append(violations, fmt.Sprintf("%v must be of type %v", path, s.Type))
+		}
+
+		if s.Type == "integer" && n != float64(int64(n)) {
+			return append(violations, fmt.Sprintf("%v must be of type integer", path))
+		}
+
+		if s.Minimum != nil && n < *s.Minimum {
+			violations = append(violations, fmt.Sprintf("%v must be at least %v", path, *s.Minimum))
+		}
+
+		if s.Maximum != nil && n > *s.Maximum {
+			violations = append(violations, fmt.Sprintf("%v must be at most %v", path, *s.Maximum))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			violations = append(violations, fmt.Sprintf("%v must be a boolean", path))
+		}
+	case "":
+		if obj, ok := v.(map[string]interface{}); ok && len(s.Properties) > 0 {
+			violations = append(violations, d.validateObject(s, obj, path, depth)...)
+		}
+	}
+
+	return violations
+}
+
+func (d *Document) validateObject(s *Schema, obj map[string]interface{}, path string, depth int) []string {
+	var violations []string
+
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			violations = append(violations, fmt.Sprintf("%v.%v is required", path, name))
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		prop, ok := s.Properties[k]
+		if !ok {
+			if additional, isBool := s.AdditionalProperties.(bool); isBool && !additional {
+				violations = append(violations, fmt.Sprintf("%v.%v is not allowed", path, k))
+			}
+
+			continue
+		}
+
+		violations = append(violations, d.validateValue(prop, obj[k], fmt.Sprintf("%v.%v", path, k), depth+1)...)
+	}
+
+	return violations
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(normalize(e)) == fmt.Sprint(v) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package openapi_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomicro/avenues/openapi"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+const validationSpec = `
+openapi: 3.0.0
+paths:
+  /orders:
+    parameters:
+      - name: X-Tenant
+        in: header
+        required: true
+        schema:
+          type: string
+    post:
+      parameters:
+        - $ref: "#/components/parameters/Dry"
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Order"
+      responses:
+        "201":
+          description: Created
+components:
+  parameters:
+    Dry:
+      name: dry
+      in: query
+      schema:
+        type: boolean
+  schemas:
+    Order:
+      type: object
+      additionalProperties: false
+      required: [status, items]
+      properties:
+        status:
+          type: string
+          enum: [open, closed]
+        note:
+          type: string
+          nullable: true
+          maxLength: 5
+        items:
+          type: array
+          minItems: 1
+          items:
+            oneOf:
+              - type: integer
+                minimum: 1
+              - type: string
+                pattern: "^sku-"
+`
+
+func TestValidate(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	g.Describe("Validation", func() {
+		var doc *openapi.Document
+
+		g.BeforeEach(func() {
+			d, err := openapi.Parse([]byte(validationSpec))
+			Expect(err).To(BeNil())
+			doc = d
+		})
+
+		request := func(url, body string) (*http.Request, []byte) {
+			req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			req.Header.Set("X-Tenant", "acme")
+
+			return req, []byte(body)
+		}
+
+		g.It("should accept a valid request", func() {
+			req, body := request("/orders?dry=true", `{"status": "open", "note": null, "items": [1, "sku-2"]}`)
+
+			Expect(doc.ValidateRequest(req, body)).To(BeEmpty())
+		})
+
+		g.It("should report every violation in a request", func() {
+			req, body := request("/orders?dry=maybe", `{"status": "lost", "note": "too long", "items": [0, "nope"], "extra": 1}`)
+			req.Header.Del("X-Tenant")
+
+			Expect(doc.ValidateRequest(req, body)).To(ConsistOf(
+				"header parameter X-Tenant is required",
+				"query parameter dry must be a boolean",
+				"request body.extra is not allowed",
+				"request body.status must be one of [open closed]",
+				"request body.note must be at most 5 characters",
+				"request body.items[0] must match exactly one schema, matched 0",
+				"request body.items[1] must match exactly one schema, matched 0",
+			))
+		})
+
+		g.It("should require the request body", func() {
+			req, body := request("/orders", "")
+
+			Expect(doc.ValidateRequest(req, body)).To(ConsistOf("request body is required"))
+		})
+
+		g.It("should report undefined content types", func() {
+			req, body := request("/orders", "status=open")
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			Expect(doc.ValidateRequest(req, body)).To(ConsistOf(`request body content type "application/x-www-form-urlencoded" is not defined`))
+		})
+
+		g.It("should report unknown operations", func() {
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+
+			Expect(doc.ValidateRequest(req, nil)).To(ConsistOf("no operation for GET /orders"))
+		})
+
+		g.It("should report undefined response statuses", func() {
+			req, _ := request("/orders", "")
+
+			Expect(doc.ValidateResponse(req, http.StatusCreated, http.Header{}, nil)).To(BeEmpty())
+			Expect(doc.ValidateResponse(req, http.StatusOK, http.Header{}, nil)).To(ConsistOf("response status 200 is not defined"))
+		})
+	})
+}
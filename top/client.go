@@ -0,0 +1,120 @@
+package top
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gomicro/avenues/config"
+)
+
+const (
+	defaultRequestsPath = "/avenues/requests"
+	defaultStreamPath   = "/avenues/stream"
+)
+
+// Client reads traffic from a running instance's admin API, sending the
+// Token as a bearer token when set
+type Client struct {
+	Addr         string
+	Token        string
+	RequestsPath string
+	StreamPath   string
+	client       *http.Client
+}
+
+// New creates a Client for the admin API at the given address, such as
+// http://localhost:4568, using the default endpoints.
+func New(addr string) *Client {
+	return &Client{
+		Addr:         strings.TrimSuffix(addr, "/"),
+		RequestsPath: defaultRequestsPath,
+		StreamPath:   defaultStreamPath,
+		client:       &http.Client{},
+	}
+}
+
+// Requests returns the requests in the instance's journal, oldest first
+func (c *Client) Requests(ctx context.Context) ([]*config.JournalEntry, error) {
+	resp, err := c.get(ctx, c.RequestsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Requests []*config.JournalEntry `json:"requests"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode requests: %v", err.Error())
+	}
+
+	return body.Requests, nil
+}
+
+// Stream sends each request the instance handles to fn until the context is
+// done or the stream ends.
+func (c *Client) Stream(ctx context.Context, fn func(*config.JournalEntry)) error {
+	resp, err := c.get(ctx, c.StreamPath)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var e config.JournalEntry
+		err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+		if err != nil {
+			return fmt.Errorf("failed to decode event: %v", err.Error())
+		}
+
+		fn(&e)
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read stream: %v", err.Error())
+	}
+
+	return fmt.Errorf("stream closed")
+}
+
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Addr+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err.Error())
+	}
+
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach avenues: %v", err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status from %v: %v", path, resp.Status)
+	}
+
+	return resp, nil
+}
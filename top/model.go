@@ -0,0 +1,422 @@
+package top
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gomicro/avenues/config"
+)
+
+const (
+	maxEntries = 1000
+
+	inverse = "\x1b[7m"
+	bold    = "\x1b[1m"
+	reset   = "\x1b[0m"
+)
+
+// Key represents a key pressed in the terminal
+type Key struct {
+	Rune rune
+	Name string
+}
+
+// Named keys
+const (
+	KeyUp        = "up"
+	KeyDown      = "down"
+	KeyEnter     = "enter"
+	KeyEscape    = "escape"
+	KeyBackspace = "backspace"
+	KeyCtrlC     = "ctrl-c"
+)
+
+// Model holds the requests seen and what the user is looking at
+type Model struct {
+	Addr      string
+	entries   []*config.JournalEntry
+	filter    string
+	editing   bool
+	draft     string
+	route     string
+	sel       int
+	connected bool
+	err       error
+}
+
+// routeStats summarises the requests for a route
+type routeStats struct {
+	route    string
+	count    int
+	classes  [6]int
+	total    float64
+	max      float64
+	lastSeen time.Time
+}
+
+// Add records a request, newest first, keeping the selection on the request
+// it was on
+func (m *Model) Add(e *config.JournalEntry) {
+	for _, existing := range m.entries {
+		if existing.ID == e.ID {
+			return
+		}
+	}
+
+	m.entries = append([]*config.JournalEntry{e}, m.entries...)
+	if len(m.entries) > maxEntries {
+		m.entries = m.entries[:maxEntries]
+	}
+
+	if m.sel > 0 && m.matches(e) {
+		m.sel++
+	}
+
+	m.clamp()
+}
+
+// Clear forgets every request, ready for the journal to be loaded again
+func (m *Model) Clear() {
+	m.entries = nil
+	m.sel = 0
+}
+
+// SetConnected records whether the stream is connected and why it is not
+func (m *Model) SetConnected(connected bool, err error) {
+	m.connected = connected
+	m.err = err
+}
+
+// HandleKey applies a key press and reports whether the user asked to quit
+func (m *Model) HandleKey(k Key) bool {
+	if k.Name == KeyCtrlC {
+		return true
+	}
+
+	if m.editing {
+		switch {
+		case k.Name == KeyEnter:
+			m.filter = m.draft
+			m.editing = false
+			m.sel = 0
+		case k.Name == KeyEscape:
+			m.editing = false
+		case k.Name == KeyBackspace:
+			if r := []rune(m.draft); len(r) > 0 {
+				m.draft = string(r[:len(r)-1])
+			}
+		case k.Rune != 0:
+			m.draft += string(k.Rune)
+		}
+
+		return false
+	}
+
+	switch {
+	case k.Rune == 'q':
+		return true
+	case k.Rune == '/':
+		m.editing = true
+		m.draft = m.filter
+	case k.Name == KeyEscape:
+		m.filter = ""
+		m.route = ""
+		m.sel = 0
+	case k.Rune == 'r':
+		m.route = m.nextRoute()
+		m.sel = 0
+	case k.Name == KeyUp || k.Rune == 'k':
+		m.sel--
+	case k.Name == KeyDown || k.Rune == 'j':
+		m.sel++
+	case k.Rune == 'g':
+		m.sel = 0
+	}
+
+	m.clamp()
+
+	return false
+}
+
+func (m *Model) clamp() {
+	n := len(m.visible())
+	if m.sel >= n {
+		m.sel = n - 1
+	}
+
+	if m.sel < 0 {
+		m.sel = 0
+	}
+}
+
+// nextRoute cycles the route filter through each route seen, then back to all
+func (m *Model) nextRoute() string {
+	stats := m.stats()
+	if len(stats) == 0 {
+		return ""
+	}
+
+	if m.route == "" {
+		return stats[0].route
+	}
+
+	for i, s := range stats {
+		if s.route == m.route && i+1 < len(stats) {
+			return stats[i+1].route
+		}
+	}
+
+	return ""
+}
+
+func (m *Model) matches(e *config.JournalEntry) bool {
+	if m.route != "" && e.Route != m.route {
+		return false
+	}
+
+	if m.filter == "" {
+		return true
+	}
+
+	f := strings.ToLower(m.filter)
+	for _, v := range []string{e.Method, e.Path, e.Route, e.Backend, strconv.Itoa(e.Status)} {
+		if strings.Contains(strings.ToLower(v), f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Model) visible() []*config.JournalEntry {
+	var out []*config.JournalEntry
+	for _, e := range m.entries {
+		if m.matches(e) {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+// Selected returns the request the detail pane shows
+func (m *Model) Selected() (*config.JournalEntry, bool) {
+	v := m.visible()
+	if len(v) == 0 {
+		return nil, false
+	}
+
+	return v[m.sel], true
+}
+
+func (m *Model) stats() []*routeStats {
+	byRoute := map[string]*routeStats{}
+	for _, e := range m.entries {
+		route := e.Route
+		if route == "" {
+			route = "(none)"
+		}
+
+		s, ok := byRoute[route]
+		if !ok {
+			s = &routeStats{route: route}
+			byRoute[route] = s
+		}
+
+		s.count++
+		if c := e.Status / 100; c > 0 && c < len(s.classes) {
+			s.classes[c]++
+		}
+
+		s.total += e.Duration
+		if e.Duration > s.max {
+			s.max = e.Duration
+		}
+
+		if e.Time.After(s.lastSeen) {
+			s.lastSeen = e.Time
+		}
+	}
+
+	stats := make([]*routeStats, 0, len(byRoute))
+	for _, s := range byRoute {
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].route < stats[j].route
+	})
+
+	return stats
+}
+
+// Render draws the model as lines fitting the given terminal size
+func (m *Model) Render(width, height int) []string {
+	var lines []string
+	add := func(style, s string) {
+		s = fit(s, width)
+		if style != "" {
+			s = style + s + reset
+		}
+
+		lines = append(lines, s)
+	}
+
+	status := "connected"
+	if !m.connected {
+		status = "disconnected"
+		if m.err != nil {
+			status += ": " + m.err.Error()
+		}
+	}
+
+	add(inverse, fmt.Sprintf(" avenues top  %v  %v requests  %v", m.Addr, len(m.entries), status))
+
+	stats := m.stats()
+	routeRows := len(stats)
+	if limit := height / 4; routeRows > limit {
+		routeRows = limit
+	}
+
+	add(bold, fmt.Sprintf(" %-30v %6v %6v %6v %6v %9v %9v", "ROUTE", "REQS", "2XX", "4XX", "5XX", "AVG", "MAX"))
+	for _, s := range stats[:routeRows] {
+		marker := " "
+		if s.route == m.route {
+			marker = ">"
+		}
+
+		add("", fmt.Sprintf("%v%-30v %6v %6v %6v %6v %9v %9v", marker, s.route, s.count, s.classes[2], s.classes[4], s.classes[5], millis(s.total/float64(s.count)), millis(s.max)))
+	}
+	add("", "")
+
+	detail := m.detail(width)
+	if limit := height / 3; len(detail) > limit {
+		detail = detail[:limit]
+	}
+
+	rows := height - len(lines) - len(detail) - 3
+	if rows < 1 {
+		rows = 1
+	}
+
+	visible := m.visible()
+	start := 0
+	if m.sel >= rows {
+		start = m.sel - rows + 1
+	}
+
+	add(bold, fmt.Sprintf(" %-8v %-7v %-28v %-16v %6v %9v", "TIME", "METHOD", "PATH", "ROUTE", "STATUS", "LATENCY"))
+	for i := start; i < len(visible) && i < start+rows; i++ {
+		e := visible[i]
+
+		style := ""
+		if i == m.sel {
+			style = inverse
+		}
+
+		add(style, fmt.Sprintf(" %-8v %-7v %-28v %-16v %6v %9v", e.Time.Local().Format("15:04:05"), e.Method, fit(e.Path, 28), fit(e.Route, 16), e.Status, millis(e.Duration)))
+	}
+
+	for len(lines) < height-len(detail)-1 {
+		add("", "")
+	}
+
+	for _, d := range detail {
+		add("", d)
+	}
+
+	footer := " q quit  / filter  r route  esc clear  j/k select"
+	switch {
+	case m.editing:
+		footer = " filter: " + m.draft + "_"
+	case m.filter != "" || m.route != "":
+		footer = fmt.Sprintf(" filter: %q  route: %q  |%v", m.filter, m.route, footer)
+	}
+	add(inverse, footer)
+
+	if len(lines) > height {
+		lines = append(lines[:height-1], lines[len(lines)-1])
+	}
+
+	return lines
+}
+
+// detail describes the selected request
+func (m *Model) detail(width int) []string {
+	e, ok := m.Selected()
+	if !ok {
+		return nil
+	}
+
+	target := e.Path
+	if e.Query != "" {
+		target += "?" + e.Query
+	}
+
+	lines := []string{
+		strings.Repeat("─", width),
+		fmt.Sprintf(" %v %v  %v  %v", e.Method, target, e.Status, millis(e.Duration)),
+		fmt.Sprintf(" route %v  backend %v  at %v", orNone(e.Route), orNone(e.Backend), e.Time.Local().Format(time.RFC3339)),
+	}
+
+	names := make([]string, 0, len(e.Headers))
+	for k := range e.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		lines = append(lines, fmt.Sprintf(" %v: %v", k, strings.Join(e.Headers[k], ", ")))
+	}
+
+	if e.Body != "" {
+		body := e.Body
+		if e.Truncated {
+			body += " …"
+		}
+
+		lines = append(lines, " "+body)
+	}
+
+	return lines
+}
+
+func millis(ms float64) string {
+	return fmt.Sprintf("%.1fms", ms)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return s
+}
+
+// fit cuts the string down to the given number of columns, replacing control
+// characters from the proxied traffic so the terminal never runs them
+func fit(s string, width int) string {
+	r := []rune(s)
+	for i, c := range r {
+		switch {
+		case c == '\t' || c == '\n' || c == '\r':
+			r[i] = ' '
+		case unicode.IsControl(c):
+			r[i] = '�'
+		}
+	}
+
+	if len(r) > width {
+		if width <= 1 {
+			return string(r[:width])
+		}
+
+		return string(r[:width-1]) + "…"
+	}
+
+	return string(r)
+}
@@ -0,0 +1,9 @@
+package top
+
+import (
+	"os"
+)
+
+// notifyResize does nothing on windows, which has no resize signal, so the
+// size is only read at start
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,264 @@
+package top
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+)
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	home        = "\x1b[H"
+	clearLine   = "\x1b[K"
+	clearBelow  = "\x1b[J"
+
+	redrawInterval = 100 * time.Millisecond
+	retryInterval  = 2 * time.Second
+)
+
+// Run shows the instance's traffic in the terminal until the user quits
+func Run(ctx context.Context, c *Client, tty *os.File, out io.Writer) error {
+	restore, err := rawMode(tty)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fmt.Fprint(out, enterScreen)
+	defer fmt.Fprint(out, leaveScreen)
+
+	m := &Model{Addr: c.Addr}
+
+	entries := make(chan *config.JournalEntry, 64)
+	states := make(chan error, 1)
+	go follow(ctx, c, entries, states)
+
+	keys := make(chan Key)
+	go readKeys(tty, keys)
+
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+
+	width, height := size(tty)
+
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+
+	dirty := true
+	for {
+		select {
+		case e := <-entries:
+			if e == nil {
+				m.Clear()
+			} else {
+				m.Add(e)
+			}
+			dirty = true
+		case err := <-states:
+			m.SetConnected(err == nil, err)
+			dirty = true
+		case k, ok := <-keys:
+			if !ok || m.HandleKey(k) {
+				return nil
+			}
+			dirty = true
+		case <-resized:
+			width, height = size(tty)
+			dirty = true
+		case <-ticker.C:
+			if !dirty {
+				continue
+			}
+
+			draw(out, m.Render(width, height))
+			dirty = false
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// follow loads the journal then streams new requests, reconnecting whenever
+// the stream drops. Connection changes are sent on states, nil once connected.
+// A nil entry is sent before the journal is loaded again, as the IDs start over
+// when Avenues restarts.
+func follow(ctx context.Context, c *Client, entries chan<- *config.JournalEntry, states chan error) {
+	report := func(err error) {
+		select {
+		case <-states:
+		default:
+		}
+		states <- err
+	}
+
+	for ctx.Err() == nil {
+		existing, err := c.Requests(ctx)
+		if err == nil {
+			report(nil)
+
+			entries <- nil
+			for _, e := range existing {
+				entries <- e
+			}
+
+			err = c.Stream(ctx, func(e *config.JournalEntry) {
+				entries <- e
+			})
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		report(err)
+
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func draw(out io.Writer, lines []string) {
+	var b bytes.Buffer
+	b.WriteString(home)
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(l)
+		b.WriteString(clearLine)
+	}
+	b.WriteString(clearBelow)
+
+	_, _ = out.Write(b.Bytes())
+}
+
+// readKeys sends each key pressed until the terminal is closed
+func readKeys(tty io.Reader, keys chan<- Key) {
+	defer close(keys)
+
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// parseKeys reads the keys in a chunk of raw terminal input
+func parseKeys(b []byte) []Key {
+	var keys []Key
+
+	for len(b) > 0 {
+		switch {
+		case bytes.HasPrefix(b, []byte("\x1b[A")), bytes.HasPrefix(b, []byte("\x1bOA")):
+			keys = append(keys, Key{Name: KeyUp})
+			b = b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[B")), bytes.HasPrefix(b, []byte("\x1bOB")):
+			keys = append(keys, Key{Name: KeyDown})
+			b = b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[")):
+			// skip other escape sequences up to their final byte
+			i := 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+
+			if i < len(b) {
+				i++
+			}
+			b = b[i:]
+		case b[0] == 0x1b:
+			keys = append(keys, Key{Name: KeyEscape})
+			b = b[1:]
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, Key{Name: KeyEnter})
+			b = b[1:]
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, Key{Name: KeyBackspace})
+			b = b[1:]
+		case b[0] == 0x03:
+			keys = append(keys, Key{Name: KeyCtrlC})
+			b = b[1:]
+		case b[0] < 0x20:
+			b = b[1:]
+		default:
+			r := []rune(string(b))[0]
+			keys = append(keys, Key{Rune: r})
+			b = b[len(string(r)):]
+		}
+	}
+
+	return keys
+}
+
+// rawMode switches the terminal to raw input, returning a func restoring its
+// previous settings
+func rawMode(tty *os.File) (func(), error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal settings: %v", err.Error())
+	}
+
+	_, err = stty(tty, "raw", "-echo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to set terminal to raw mode: %v", err.Error())
+	}
+
+	return func() {
+		_, _ = stty(tty, strings.TrimSpace(saved))
+	}, nil
+}
+
+// size returns the terminal's columns and rows, falling back to 80x24
+func size(tty *os.File) (int, int) {
+	out, err := stty(tty, "size")
+	if err != nil {
+		return 80, 24
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 80, 24
+	}
+
+	rows, err1 := strconv.Atoi(fields[0])
+	cols, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows == 0 || cols == 0 {
+		return 80, 24
+	}
+
+	return cols, rows
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
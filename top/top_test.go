@@ -0,0 +1,188 @@
+package top_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomicro/avenues/config"
+	"github.com/gomicro/avenues/top"
+
+	"github.com/franela/goblin"
+	. "github.com/onsi/gomega"
+)
+
+func TestTop(t *testing.T) {
+	g := goblin.Goblin(t)
+	RegisterFailHandler(func(m string, _ ...int) { g.Fail(m) })
+
+	entry := func(id int64, route, path string, status int, ms float64) *config.JournalEntry {
+		return &config.JournalEntry{
+			ID:       id,
+			Time:     time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC),
+			Method:   "GET",
+			Path:     path,
+			Route:    route,
+			Backend:  "http://service:4567" + path,
+			Status:   status,
+			Duration: ms,
+			Headers:  http.Header{"Accept": {"application/json"}},
+		}
+	}
+
+	g.Describe("Client", func() {
+		g.It("should read the journal and follow the stream", func() {
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer s3cret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				switch r.URL.Path {
+				case "/avenues/requests":
+					fmt.Fprint(w, `{"requests":[{"id":1,"method":"GET","path":"/v1/users","status":200}]}`)
+				case "/avenues/stream":
+					w.Header().Set("Content-Type", "text/event-stream")
+					fmt.Fprint(w, ": connected\n\n")
+					fmt.Fprint(w, "id: 2\nevent: request\ndata: {\"id\":2,\"method\":\"POST\",\"path\":\"/v1/teams\",\"status\":201}\n\n")
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer backend.Close()
+
+			c := top.New(backend.URL + "/")
+
+			_, err := c.Requests(context.Background())
+			Expect(err).NotTo(BeNil())
+
+			c.Token = "s3cret"
+			entries, err := c.Requests(context.Background())
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Path).To(Equal("/v1/users"))
+
+			var streamed []*config.JournalEntry
+			err = c.Stream(context.Background(), func(e *config.JournalEntry) {
+				streamed = append(streamed, e)
+			})
+			Expect(err).NotTo(BeNil())
+			Expect(streamed).To(HaveLen(1))
+			Expect(streamed[0].Status).To(Equal(http.StatusCreated))
+
+			c.StreamPath = "/missing"
+			err = c.Stream(context.Background(), func(e *config.JournalEntry) {})
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("404"))
+		})
+	})
+
+	g.Describe("Model", func() {
+		var m *top.Model
+
+		g.BeforeEach(func() {
+			m = &top.Model{Addr: "http://localhost:4567"}
+			m.SetConnected(true, nil)
+
+			m.Add(entry(1, "/v1/users", "/v1/users/1", 200, 10))
+			m.Add(entry(2, "/v1/teams", "/v1/teams/1", 503, 30))
+			m.Add(entry(3, "/v1/users", "/v1/users/2", 404, 20))
+		})
+
+		g.It("should summarise requests per route", func() {
+			out := strings.Join(m.Render(120, 40), "\n")
+
+			Expect(out).To(ContainSubstring("3 requests"))
+			Expect(out).To(MatchRegexp(`/v1/teams\s+1\s+0\s+0\s+1\s+30.0ms\s+30.0ms`))
+			Expect(out).To(MatchRegexp(`/v1/users\s+2\s+1\s+1\s+0\s+15.0ms\s+20.0ms`))
+		})
+
+		g.It("should select requests and describe them", func() {
+			e, ok := m.Selected()
+			Expect(ok).To(BeTrue())
+			Expect(e.ID).To(Equal(int64(3)))
+
+			m.HandleKey(top.Key{Name: top.KeyDown})
+			m.HandleKey(top.Key{Rune: 'j'})
+			m.HandleKey(top.Key{Rune: 'j'})
+			e, _ = m.Selected()
+			Expect(e.ID).To(Equal(int64(1)))
+
+			m.HandleKey(top.Key{Rune: 'k'})
+			e, _ = m.Selected()
+			Expect(e.ID).To(Equal(int64(2)))
+
+			m.Add(entry(4, "/v1/users", "/v1/users/3", 200, 5))
+			e, _ = m.Selected()
+			Expect(e.ID).To(Equal(int64(2)))
+
+			out := strings.Join(m.Render(120, 40), "\n")
+			Expect(out).To(ContainSubstring("GET /v1/teams/1  503  30.0ms"))
+			Expect(out).To(ContainSubstring("backend http://service:4567/v1/teams/1"))
+			Expect(out).To(ContainSubstring("Accept: application/json"))
+		})
+
+		g.It("should filter requests by text and route", func() {
+			m.HandleKey(top.Key{Rune: '/'})
+			for _, r := range "503" {
+				m.HandleKey(top.Key{Rune: r})
+			}
+			m.HandleKey(top.Key{Name: top.KeyEnter})
+
+			e, _ := m.Selected()
+			Expect(e.ID).To(Equal(int64(2)))
+
+			m.HandleKey(top.Key{Name: top.KeyEscape})
+			m.HandleKey(top.Key{Rune: 'r'})
+			m.HandleKey(top.Key{Rune: 'r'})
+
+			e, _ = m.Selected()
+			Expect(e.Route).To(Equal("/v1/users"))
+			Expect(strings.Join(m.Render(120, 40), "\n")).To(ContainSubstring(`route: "/v1/users"`))
+
+			m.HandleKey(top.Key{Rune: 'r'})
+			Expect(strings.Join(m.Render(120, 40), "\n")).NotTo(ContainSubstring(`route: "/v1/users"`))
+		})
+
+		g.It("should forget requests when the journal is loaded again", func() {
+			m.HandleKey(top.Key{Rune: 'j'})
+			m.Clear()
+			Expect(m.Render(120, 40)[0]).To(ContainSubstring("0 requests"))
+
+			m.Add(entry(1, "/v1/posts", "/v1/posts", 200, 5))
+			e, ok := m.Selected()
+			Expect(ok).To(BeTrue())
+			Expect(e.Route).To(Equal("/v1/posts"))
+		})
+
+		g.It("should not pass control characters from the traffic to the terminal", func() {
+			e := entry(4, "/v1/users", "/v1/users/\x1b[2J", 200, 5)
+			e.Headers.Set("X-Debug", "one\rtwo")
+			e.Body = "\x1b]0;title\x07line\nnext"
+			m.Add(e)
+
+			out := strings.Join(m.Render(120, 40), "\n")
+			Expect(out).NotTo(ContainSubstring("\x1b[2J"))
+			Expect(out).NotTo(ContainSubstring("\x1b]0"))
+			Expect(out).NotTo(ContainSubstring("\r"))
+			Expect(out).NotTo(ContainSubstring("\x07"))
+			Expect(out).To(ContainSubstring("X-Debug: one two"))
+			Expect(out).To(ContainSubstring("�]0;title�line next"))
+		})
+
+		g.It("should fit the terminal", func() {
+			lines := m.Render(40, 12)
+			Expect(len(lines)).To(BeNumerically("<=", 12))
+		})
+
+		g.It("should quit on q or ctrl-c", func() {
+			Expect(m.HandleKey(top.Key{Rune: 'x'})).To(BeFalse())
+			Expect(m.HandleKey(top.Key{Rune: 'q'})).To(BeTrue())
+			Expect(m.HandleKey(top.Key{Name: top.KeyCtrlC})).To(BeTrue())
+		})
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gomicro/avenues/top"
+)
+
+// topTraffic shows a live view of the requests a running instance handles,
+// read from its admin API.
+func topTraffic(args []string) {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	addr := fs.String("addr", "http://localhost:4568", "admin address of the running avenues")
+	token := fs.String("token", "", "admin token, if one is required")
+	requests := fs.String("requests", "/avenues/requests", "path of the requests endpoint")
+	stream := fs.String("stream", "/avenues/stream", "path of the stream endpoint")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: avenues top [-addr http://localhost:4568] [-token token]")
+		fs.PrintDefaults()
+	}
+
+	_ = fs.Parse(args)
+
+	c := top.New(*addr)
+	c.Token = *token
+	c.RequestsPath = *requests
+	c.StreamPath = *stream
+
+	err := top.Run(context.Background(), c, os.Stdin, os.Stdout)
+	if err != nil {
+		fatalf("Failed to run top: %v", err.Error())
+	}
+}